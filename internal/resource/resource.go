@@ -0,0 +1,125 @@
+// Package resource describes structs annotated with google/jsonapi `jsonapi` tags
+package resource
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Resource describes a struct annotated with jsonapi tags
+type Resource struct {
+	Type          reflect.Type
+	Name          string
+	Primary       *Field
+	Attributes    []*Field
+	Relationships []*Field
+}
+
+// Field is a struct field annotated with a jsonapi `primary`, `attr` or `relation` tag
+type Field struct {
+	Name    string
+	Index   int
+	Type    reflect.Type
+	Options []string
+	// Many reports whether a relationship is to-many
+	Many bool
+}
+
+var resources sync.Map // reflect.Type -> *Resource
+
+// Of returns the resource described by `t`, a struct or a pointer (or slice of pointers) to a struct,
+// or nil if `t` is not annotated with jsonapi tags
+func Of(t reflect.Type) *Resource {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if res, ok := resources.Load(t); ok {
+		return res.(*Resource)
+	}
+
+	res := &Resource{Type: t}
+	for i := 0; i < t.NumField(); i++ {
+		args := strings.Split(t.Field(i).Tag.Get("jsonapi"), ",")
+		if len(args) < 2 {
+			continue
+		}
+		f := &Field{Name: args[1], Index: i, Type: t.Field(i).Type, Options: args[2:]}
+		switch args[0] {
+		case "primary":
+			res.Name, res.Primary = args[1], f
+		case "attr":
+			res.Attributes = append(res.Attributes, f)
+		case "relation":
+			f.Many = f.Type.Kind() == reflect.Slice
+			res.Relationships = append(res.Relationships, f)
+		}
+	}
+	if res.Primary == nil {
+		return nil
+	}
+
+	resources.Store(t, res)
+	return res
+}
+
+// Attribute returns the attribute named `name`, or nil
+func (res *Resource) Attribute(name string) *Field {
+	for _, f := range res.Attributes {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Relationship returns the relationship named `name`, or nil
+func (res *Resource) Relationship(name string) *Field {
+	for _, f := range res.Relationships {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Related returns the resource a relationship points to
+func (f *Field) Related() *Resource {
+	return Of(f.Type)
+}
+
+// HasOption reports whether the tag of the field has `option`, e.g. `omitempty`
+func (f *Field) HasOption(option string) bool {
+	for _, o := range f.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Reachable returns the resources reachable from `res` through relationships, including itself, keyed by type
+func (res *Resource) Reachable() map[string]*Resource {
+	reachable := map[string]*Resource{}
+	var visit func(res *Resource)
+	visit = func(res *Resource) {
+		if res == nil {
+			return
+		}
+		if _, ok := reachable[res.Name]; ok {
+			return
+		}
+		reachable[res.Name] = res
+		for _, rel := range res.Relationships {
+			visit(rel.Related())
+		}
+	}
+	visit(res)
+	return reachable
+}
@@ -0,0 +1,55 @@
+package resource_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type Blog struct {
+	ID          int     `jsonapi:"primary,blogs"`
+	Title       string  `jsonapi:"attr,title,omitempty"`
+	Posts       []*Post `jsonapi:"relation,posts"`
+	CurrentPost *Post   `jsonapi:"relation,current_post"`
+	Internal    string
+}
+
+type Post struct {
+	ID    int    `jsonapi:"primary,posts"`
+	Blog  *Blog  `jsonapi:"relation,blog"`
+	Title string `jsonapi:"attr,title"`
+}
+
+func TestOf(t *testing.T) {
+	for _, v := range []interface{}{Blog{}, &Blog{}, []*Blog{}} {
+		res := resource.Of(reflect.TypeOf(v))
+		if assert.NotNil(t, res) {
+			assert.Equal(t, "blogs", res.Name)
+			assert.Equal(t, reflect.TypeOf(Blog{}), res.Type)
+			assert.Equal(t, 0, res.Primary.Index)
+		}
+	}
+
+	res := resource.Of(reflect.TypeOf(&Blog{}))
+	title := res.Attribute("title")
+	if assert.NotNil(t, title) {
+		assert.Equal(t, 1, title.Index)
+		assert.True(t, title.HasOption("omitempty"))
+	}
+	assert.Nil(t, res.Attribute("posts"))
+	assert.True(t, res.Relationship("posts").Many)
+	assert.False(t, res.Relationship("current_post").Many)
+	assert.Equal(t, "posts", res.Relationship("current_post").Related().Name)
+
+	assert.Nil(t, resource.Of(reflect.TypeOf(struct{}{})))
+	assert.Nil(t, resource.Of(reflect.TypeOf("")))
+	assert.Nil(t, resource.Of(nil))
+}
+
+func TestResource_Reachable(t *testing.T) {
+	reachable := resource.Of(reflect.TypeOf(&Blog{})).Reachable()
+	assert.Len(t, reachable, 2)
+	assert.Contains(t, reachable, "blogs")
+	assert.Contains(t, reachable, "posts")
+}
@@ -0,0 +1,235 @@
+package queryparams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	chi_render "github.com/go-chi/render"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter operators, given as `filter[FIELD][OPERATOR]`
+const (
+	OpEq      = "eq"
+	OpNe      = "ne"
+	OpLt      = "lt"
+	OpLte     = "lte"
+	OpGt      = "gt"
+	OpGte     = "gte"
+	OpIn      = "in"
+	OpBetween = "between"
+	OpLike    = "like"
+)
+
+// Operators lists the operators supported by default for each kind of attribute,
+// `like` is only supported by string attributes
+var Operators = []string{OpEq, OpNe, OpLt, OpLte, OpGt, OpGte, OpIn, OpBetween}
+
+// ConditionsCtxKey is the context key holding the []Condition parsed by FilterBy
+var ConditionsCtxKey = &contextKey{"Conditions"}
+
+// Filterable is implemented by resources restricting the attributes clients can filter by,
+// keyed by attribute name with the allowed operators. By default every attribute can be filtered
+// with the operators supported by its type
+type Filterable interface {
+	JSONAPIFilterable() map[string][]string
+}
+
+// Condition is a filter criteria whose values are converted to the Go type of the attribute,
+// conditions of a request are combined with a logical AND
+type Condition struct {
+	Field string
+	// Operator is the one of the filter, empty (`eq`) when not given
+	Operator string
+	// Values holds a single value but for `in` (one or more values) and `between` (exactly two values)
+	Values []interface{}
+}
+
+// Parameter returns the query parameter of the condition, see Filter.Parameter
+func (c Condition) Parameter() string {
+	return filterParameter(c.Field, c.Operator)
+}
+
+// Parameter returns the query parameter of the filter as reported in errors:
+// `filter[FIELD]` without an operator and `filter[FIELD][OPERATOR]` otherwise
+func (f Filter) Parameter() string {
+	return filterParameter(f.Field, f.Operator)
+}
+
+func filterParameter(field, operator string) string {
+	param := ParamFilter + "[" + field + "]"
+	if operator != "" {
+		param += "[" + operator + "]"
+	}
+	return param
+}
+
+// ParseFilter converts `filters` into a flat list of conditions on the `attr` fields of `model`, a struct pointer
+// or a slice of struct pointers, that are combined with a logical AND (there are no OR groups nor nesting).
+// Filters without an operator use `eq`. A 400 Bad Request error is returned for each filter on an unknown
+// or not filterable attribute, with an unsupported operator or with values that cannot be converted
+func ParseFilter(filters []Filter, model interface{}) ([]Condition, error) {
+	res := resource.Of(reflect.TypeOf(model))
+	if res == nil {
+		return nil, ErrNotResource
+	}
+	var filterable map[string][]string
+	if f, ok := reflect.New(res.Type).Interface().(Filterable); ok {
+		filterable = f.JSONAPIFilterable()
+	}
+
+	var errs render.Errors
+	conditions := []Condition{}
+	for _, f := range filters {
+		condition, err := parseCondition(f, res, filterable)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return conditions, nil
+}
+
+// FilterBy is a middleware converting the request filters into conditions on the attributes of `model`
+// (see ParseFilter), the conditions are stored in the request context (see GetConditions).
+// Invalid filters are responded with a JSON:API 400 Bad Request error
+func FilterBy(model interface{}) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			params := FromContext(r.Context())
+			var err error
+			if params == nil {
+				params, err = Parse(r.URL.Query())
+			}
+			var conditions []Condition
+			if err == nil {
+				conditions, err = ParseFilter(params.Filter, model)
+			}
+			if err != nil {
+				chi_render.Status(r, http.StatusBadRequest)
+				render.JSONAPI(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ConditionsCtxKey, conditions)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// GetConditions returns the conditions stored by FilterBy, or nil if there are none
+func GetConditions(ctx context.Context) []Condition {
+	conditions, _ := ctx.Value(ConditionsCtxKey).([]Condition)
+	return conditions
+}
+
+func parseCondition(f Filter, res *resource.Resource, filterable map[string][]string) (Condition, error) {
+	param := f.Parameter()
+	condition := Condition{Field: f.Field, Operator: f.Operator}
+	operator := f.Operator
+	if operator == "" {
+		operator = OpEq
+	}
+
+	attr := res.Attribute(f.Field)
+	allowed, ok := filterable[f.Field]
+	if attr == nil || (filterable != nil && !ok) || !sortable(attr.Type) {
+		return condition, &Error{Parameter: param, Detail: fmt.Sprintf("filtering by %q is not allowed", f.Field)}
+	}
+	if filterable == nil {
+		allowed = operators(attr.Type)
+	}
+	if !contains(allowed, operator) || !contains(operators(attr.Type), operator) {
+		return condition, &Error{Parameter: param, Detail: fmt.Sprintf("unsupported operator %q, expected one of %s", operator, strings.Join(allowed, ", "))}
+	}
+
+	values := []string{f.Value}
+	switch operator {
+	case OpIn:
+		values = strings.Split(f.Value, ",")
+	case OpBetween:
+		if values = strings.Split(f.Value, ","); len(values) != 2 {
+			return condition, &Error{Parameter: param, Detail: "must be given as two comma separated values"}
+		}
+	}
+	for _, s := range values {
+		v, err := convertValue(s, attr.Type)
+		if err != nil {
+			return condition, &Error{Parameter: param, Detail: fmt.Sprintf("invalid value %q: %s", s, err)}
+		}
+		condition.Values = append(condition.Values, v)
+	}
+	return condition, nil
+}
+
+// operators returns the operators supported by attributes of type `t`
+func operators(t reflect.Type) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return []string{OpEq, OpNe}
+	case reflect.String:
+		return append(append([]string{}, Operators...), OpLike)
+	}
+	return Operators
+}
+
+// convertValue converts `s` into a value of type `t`, or of the type `t` points to.
+// Times are given as RFC 3339 strings or unix timestamps
+func convertValue(s string, t reflect.Type) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		if v, err := time.Parse(time.RFC3339, s); err == nil {
+			return v, nil
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, errors.New("expected a RFC 3339 time or a unix timestamp")
+		}
+		return time.Unix(i, 0), nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("expected a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return nil, errors.New("expected an integer")
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return nil, errors.New("expected a positive integer")
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return nil, errors.New("expected a number")
+		}
+		v.SetFloat(f)
+	}
+	return v.Interface(), nil
+}
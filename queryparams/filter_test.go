@@ -0,0 +1,161 @@
+package queryparams_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/queryparams"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Author struct {
+	ID     int    `jsonapi:"primary,authors"`
+	Name   string `jsonapi:"attr,name"`
+	Email  string `jsonapi:"attr,email"`
+	Active bool   `jsonapi:"attr,active"`
+}
+
+func (a *Author) JSONAPIFilterable() map[string][]string {
+	return map[string][]string{
+		"name":   {queryparams.OpEq, queryparams.OpLike},
+		"active": {queryparams.OpEq},
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		filters            []queryparams.Filter
+		model              interface{}
+		expectedConditions []queryparams.Condition
+		expectedParameters []string
+	}{
+		{
+			name: "typed values",
+			filters: []queryparams.Filter{
+				{Field: "title", Operator: "eq", Value: "foo"},
+				{Field: "view_count", Operator: "gt", Value: "10"},
+				{Field: "rating", Operator: "lte", Value: "4.5"},
+				{Field: "created_at", Operator: "between", Value: "2020-01-02T03:04:05Z,1577934245"},
+				{Field: "title", Operator: "in", Value: "a,b,c"},
+			},
+			model: []*Blog{},
+			expectedConditions: []queryparams.Condition{
+				{Field: "title", Operator: "eq", Values: []interface{}{"foo"}},
+				{Field: "view_count", Operator: "gt", Values: []interface{}{10}},
+				{Field: "rating", Operator: "lte", Values: []interface{}{4.5}},
+				{Field: "created_at", Operator: "between", Values: []interface{}{created, time.Unix(1577934245, 0)}},
+				{Field: "title", Operator: "in", Values: []interface{}{"a", "b", "c"}},
+			},
+		},
+		{
+			name:               "no filters",
+			model:              &Blog{},
+			expectedConditions: []queryparams.Condition{},
+		},
+		{
+			name: "allow-list",
+			filters: []queryparams.Filter{
+				{Field: "name", Operator: "like", Value: "jo%"},
+				{Field: "active", Operator: "eq", Value: "true"},
+			},
+			model: &Author{},
+			expectedConditions: []queryparams.Condition{
+				{Field: "name", Operator: "like", Values: []interface{}{"jo%"}},
+				{Field: "active", Operator: "eq", Values: []interface{}{true}},
+			},
+		},
+		{
+			name: "invalid filters",
+			filters: []queryparams.Filter{
+				{Field: "posts", Value: "1"},
+				{Field: "tags", Value: "go"},
+				{Field: "view_count", Operator: "like", Value: "1"},
+				{Field: "view_count", Value: "many"},
+				{Field: "view_count", Operator: "eq", Value: "many"},
+				{Field: "created_at", Operator: "between", Value: "1577934245"},
+				{Field: "rating", Value: "high"},
+			},
+			model:              &Blog{},
+			expectedParameters: []string{"filter[posts]", "filter[tags]", "filter[view_count][like]", "filter[view_count]", "filter[view_count][eq]", "filter[created_at][between]", "filter[rating]"},
+		},
+		{
+			name: "not in allow-list",
+			filters: []queryparams.Filter{
+				{Field: "email", Value: "jo@example.com"},
+				{Field: "active", Operator: "ne", Value: "true"},
+			},
+			model:              &Author{},
+			expectedParameters: []string{"filter[email]", "filter[active][ne]"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conditions, err := queryparams.ParseFilter(test.filters, test.model)
+			if test.expectedParameters == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedConditions, conditions)
+				return
+			}
+			var parameters []string
+			if assert.IsType(t, render.Errors{}, err) {
+				for _, e := range err.(render.Errors) {
+					parameters = append(parameters, e.(*queryparams.Error).Parameter)
+				}
+			}
+			assert.Equal(t, test.expectedParameters, parameters)
+		})
+	}
+
+	t.Run("should fail on non resources", func(t *testing.T) {
+		_, err := queryparams.ParseFilter(nil, &struct{}{})
+		assert.Equal(t, queryparams.ErrNotResource, err)
+	})
+}
+
+func TestFilterBy(t *testing.T) {
+
+	t.Run("should store the conditions in the request context", func(t *testing.T) {
+		var conditions []queryparams.Condition
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conditions = queryparams.GetConditions(r.Context())
+		})
+		r := httptest.NewRequest(http.MethodGet, "/blogs?filter[view_count][gte]=3", nil)
+		w := httptest.NewRecorder()
+		queryparams.FilterBy(&Blog{})(next).ServeHTTP(w, r)
+		assert.Equal(t, []queryparams.Condition{{Field: "view_count", Operator: "gte", Values: []interface{}{3}}}, conditions)
+	})
+
+	t.Run("should respond 400 on invalid filters", func(t *testing.T) {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("next handler should not be called")
+		})
+		r := httptest.NewRequest(http.MethodGet, "/authors?filter[email]=jo@example.com", nil)
+		w := httptest.NewRecorder()
+		queryparams.Middleware(queryparams.FilterBy(&Author{})(next)).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"invalid query parameter \"filter[email]\": filtering by \"email\" is not allowed","status":"400","code":"invalid_query_parameter","source":{"parameter":"filter[email]"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+}
+
+func TestFilter_Parameter(t *testing.T) {
+	t.Run("should report the parameter as given", func(t *testing.T) {
+		for query, expected := range map[string]string{"filter[title]=foo": "filter[title]", "filter[title][eq]=foo": "filter[title][eq]"} {
+			values, _ := url.ParseQuery(query)
+			params, err := queryparams.Parse(values)
+			if assert.NoError(t, err) {
+				assert.Equal(t, expected, params.Filter[0].Parameter())
+			}
+		}
+	})
+	t.Run("should report other operators", func(t *testing.T) {
+		assert.Equal(t, "filter[title][like]", queryparams.Condition{Field: "title", Operator: "like"}.Parameter())
+	})
+}
@@ -0,0 +1,53 @@
+package queryparams
+
+import (
+	"context"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	chi_render "github.com/go-chi/render"
+	"net/http"
+)
+
+// ParamsCtxKey is the context key holding the parsed *Params of a request
+var ParamsCtxKey = &contextKey{"QueryParams"}
+
+// Middleware parses the JSON:API query parameters and stores them in the request context,
+// malformed parameters are responded with a JSON:API 400 Bad Request error.
+// Includes and sparse fieldsets are also set with render.Include and render.Fields so that render.JSONAPI applies them
+func Middleware(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		params, err := Parse(r.URL.Query())
+		if err != nil {
+			chi_render.Status(r, http.StatusBadRequest)
+			render.JSONAPI(w, r, err)
+			return
+		}
+		if params.Include != nil {
+			render.Include(r, params.Include)
+		}
+		if len(params.Fields) > 0 {
+			render.Fields(r, params.Fields)
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), params)))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// NewContext returns a copy of `ctx` holding `params`
+func NewContext(ctx context.Context, params *Params) context.Context {
+	return context.WithValue(ctx, ParamsCtxKey, params)
+}
+
+// FromContext returns the *Params stored by Middleware, or nil if there are none
+func FromContext(ctx context.Context) *Params {
+	params, _ := ctx.Value(ParamsCtxKey).(*Params)
+	return params
+}
+
+// contextKey is a value for use with context.WithValue
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string {
+	return "jsonapi queryparams context value " + k.name
+}
@@ -0,0 +1,56 @@
+package queryparams_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/queryparams"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+
+	t.Run("should store parsed parameters in context", func(t *testing.T) {
+		var params *queryparams.Params
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params = queryparams.FromContext(r.Context())
+		})
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs?include=posts&sort=-title", nil)
+		w := httptest.NewRecorder()
+		queryparams.Middleware(next).ServeHTTP(w, r)
+		if assert.NotNil(t, params) {
+			assert.Equal(t, []string{"posts"}, params.Include)
+			assert.Equal(t, []queryparams.SortField{{Field: "title", Descending: true}}, params.Sort)
+		}
+	})
+
+	t.Run("should set sparse fieldsets for render.JSONAPI", func(t *testing.T) {
+		var fields map[string][]string
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fields, _ = r.Context().Value(render.FieldsCtxKey).(map[string][]string)
+		})
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs?fields[blogs]=title", nil)
+		w := httptest.NewRecorder()
+		queryparams.Middleware(next).ServeHTTP(w, r)
+		assert.Equal(t, map[string][]string{"blogs": {"title"}}, fields)
+	})
+
+	t.Run("should respond 400 on malformed parameters", func(t *testing.T) {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("next handler should not be called")
+		})
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs?fields=title", nil)
+		w := httptest.NewRecorder()
+		queryparams.Middleware(next).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "application/vnd.api+json", w.Header().Get("Content-Type"))
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"invalid query parameter \"fields\": must be given as fields[TYPE]","status":"400","code":"invalid_query_parameter","source":{"parameter":"fields"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should be nil when middleware is not used", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs", nil)
+		assert.Nil(t, queryparams.FromContext(r.Context()))
+	})
+}
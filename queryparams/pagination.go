@@ -0,0 +1,290 @@
+package queryparams
+
+import (
+	"context"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	chi_render "github.com/go-chi/render"
+	"github.com/google/jsonapi"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultPageSize is the page size used by strategies without a default size
+const DefaultPageSize = 20
+
+// maxInt is the largest int, requested pages are bounded so that the offset of the next page does not overflow it
+const maxInt = int(^uint(0) >> 1)
+
+// PageCtxKey is the context key holding the *Page requested by the client, see Paginate
+var PageCtxKey = &contextKey{"Page"}
+
+// Strategy is a pagination strategy, see https://jsonapi.org/format/#fetching-pagination
+type Strategy interface {
+	// Parse returns the page requested with the `page[NAME]` query parameters, keyed by NAME
+	Parse(page map[string]string) (*Page, error)
+	// Links returns the pagination links of `page` built from the request URL `u`,
+	// `count` is the number of resources in the page
+	Links(page *Page, u *url.URL, count int) jsonapi.Links
+}
+
+// Page is the page requested by a client, every strategy sets Offset and Limit but the cursor-based one
+// which sets Limit, After and Before
+type Page struct {
+	// Number and Size are set by the page-based strategy, Number starts at 1
+	Number int
+	Size   int
+	Offset int
+	Limit  int
+	// After and Before are the cursors set by the cursor-based strategy
+	After  string
+	Before string
+	// Total is the total number of resources, when set by the handler the `last` link is rendered
+	Total *int
+	// PrevCursor and NextCursor are set by the handler, for the cursor-based strategy, to the cursors
+	// of the first and last resources of the page so that `prev` and `next` links are rendered
+	PrevCursor string
+	NextCursor string
+
+	strategy Strategy
+}
+
+// SetTotal sets the total number of resources
+func (p *Page) SetTotal(total int) {
+	p.Total = &total
+}
+
+// PaginationLinks implements render.Paginator
+func (p *Page) PaginationLinks(u *url.URL, count int) jsonapi.Links {
+	if p.strategy == nil {
+		return nil
+	}
+	return p.strategy.Links(p, u, count)
+}
+
+// PageNumber is the page-based strategy using `page[number]` (starting at 1) and `page[size]`
+type PageNumber struct {
+	// DefaultSize defaults to DefaultPageSize, MaxSize is unlimited when 0
+	DefaultSize int
+	MaxSize     int
+}
+
+// Parse implements Strategy
+func (s PageNumber) Parse(page map[string]string) (*Page, error) {
+	if err := allowPageParams(page, "number", "size"); err != nil {
+		return nil, err
+	}
+	number, err := pageInt(page, "number", 1, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	size, err := pageInt(page, "size", defaultSize(s.DefaultSize), 1, s.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	if number > maxInt/size {
+		return nil, &Error{Parameter: ParamPage + "[number]", Detail: fmt.Sprintf("must not be greater than %d", maxInt/size)}
+	}
+	return &Page{Number: number, Size: size, Offset: (number - 1) * size, Limit: size, strategy: s}, nil
+}
+
+// Links implements Strategy
+func (s PageNumber) Links(page *Page, u *url.URL, count int) jsonapi.Links {
+	link := func(number int) string {
+		return pageLink(u, map[string]string{"number": strconv.Itoa(number), "size": strconv.Itoa(page.Size)})
+	}
+	links := jsonapi.Links{jsonapi.KeyFirstPage: link(1)}
+	if page.Number > 1 {
+		links[jsonapi.KeyPreviousPage] = link(page.Number - 1)
+	}
+	if page.Total != nil {
+		last := (*page.Total + page.Size - 1) / page.Size
+		if last < 1 {
+			last = 1
+		}
+		links[jsonapi.KeyLastPage] = link(last)
+		if page.Number < last {
+			links[jsonapi.KeyNextPage] = link(page.Number + 1)
+		}
+	} else if count >= page.Size {
+		links[jsonapi.KeyNextPage] = link(page.Number + 1)
+	}
+	return links
+}
+
+// OffsetLimit is the offset-based strategy using `page[offset]` (starting at 0) and `page[limit]`
+type OffsetLimit struct {
+	// DefaultLimit defaults to DefaultPageSize, MaxLimit is unlimited when 0
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Parse implements Strategy
+func (s OffsetLimit) Parse(page map[string]string) (*Page, error) {
+	if err := allowPageParams(page, "offset", "limit"); err != nil {
+		return nil, err
+	}
+	offset, err := pageInt(page, "offset", 0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := pageInt(page, "limit", defaultSize(s.DefaultLimit), 1, s.MaxLimit)
+	if err != nil {
+		return nil, err
+	}
+	if offset > maxInt-limit {
+		return nil, &Error{Parameter: ParamPage + "[offset]", Detail: fmt.Sprintf("must not be greater than %d", maxInt-limit)}
+	}
+	return &Page{Offset: offset, Limit: limit, strategy: s}, nil
+}
+
+// Links implements Strategy
+func (s OffsetLimit) Links(page *Page, u *url.URL, count int) jsonapi.Links {
+	link := func(offset int) string {
+		return pageLink(u, map[string]string{"offset": strconv.Itoa(offset), "limit": strconv.Itoa(page.Limit)})
+	}
+	links := jsonapi.Links{jsonapi.KeyFirstPage: link(0)}
+	if page.Offset > 0 {
+		prev := page.Offset - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links[jsonapi.KeyPreviousPage] = link(prev)
+	}
+	if page.Total != nil {
+		last := 0
+		if *page.Total > 0 {
+			last = (*page.Total - 1) / page.Limit * page.Limit
+		}
+		links[jsonapi.KeyLastPage] = link(last)
+		if page.Offset+page.Limit < *page.Total {
+			links[jsonapi.KeyNextPage] = link(page.Offset + page.Limit)
+		}
+	} else if count >= page.Limit {
+		links[jsonapi.KeyNextPage] = link(page.Offset + page.Limit)
+	}
+	return links
+}
+
+// Cursor is the cursor-based strategy using `page[after]`, `page[before]` and `page[size]`
+type Cursor struct {
+	// DefaultSize defaults to DefaultPageSize, MaxSize is unlimited when 0
+	DefaultSize int
+	MaxSize     int
+}
+
+// Parse implements Strategy
+func (s Cursor) Parse(page map[string]string) (*Page, error) {
+	if err := allowPageParams(page, "after", "before", "size"); err != nil {
+		return nil, err
+	}
+	size, err := pageInt(page, "size", defaultSize(s.DefaultSize), 1, s.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Size: size, Limit: size, After: page["after"], Before: page["before"], strategy: s}, nil
+}
+
+// Links implements Strategy, `prev` and `next` are only rendered when the handler sets the page cursors
+func (s Cursor) Links(page *Page, u *url.URL, count int) jsonapi.Links {
+	size := strconv.Itoa(page.Size)
+	links := jsonapi.Links{jsonapi.KeyFirstPage: pageLink(u, map[string]string{"size": size})}
+	if page.PrevCursor != "" {
+		links[jsonapi.KeyPreviousPage] = pageLink(u, map[string]string{"before": page.PrevCursor, "size": size})
+	}
+	if page.NextCursor != "" {
+		links[jsonapi.KeyNextPage] = pageLink(u, map[string]string{"after": page.NextCursor, "size": size})
+	}
+	return links
+}
+
+// Paginate is a middleware parsing the requested page with `strategy`, the page is stored in the request
+// context (see GetPage) and set with render.Pagination so that render.JSONAPI adds pagination links
+// to collection payloads. Invalid page parameters are responded with a JSON:API 400 Bad Request error
+func Paginate(strategy Strategy) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			params := FromContext(r.Context())
+			var err error
+			if params == nil {
+				params, err = Parse(r.URL.Query())
+			}
+			var page *Page
+			if err == nil {
+				page, err = strategy.Parse(params.Page)
+			}
+			if err != nil {
+				chi_render.Status(r, http.StatusBadRequest)
+				render.JSONAPI(w, r, err)
+				return
+			}
+			render.Pagination(r, page)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), PageCtxKey, page)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// GetPage returns the *Page stored by Paginate, or nil if there is none
+func GetPage(ctx context.Context) *Page {
+	page, _ := ctx.Value(PageCtxKey).(*Page)
+	return page
+}
+
+func defaultSize(size int) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	return size
+}
+
+// allowPageParams returns an error for `page[NAME]` parameters not in `names`
+func allowPageParams(page map[string]string, names ...string) error {
+	var unknown []string
+	for name := range page {
+		if !contains(names, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &Error{Parameter: ParamPage + "[" + unknown[0] + "]", Detail: "unsupported pagination parameter, expected one of " + strings.Join(names, ", ")}
+}
+
+// pageInt returns the integer `page[name]`, `def` when not given, checked against `min` and `max` (when not 0)
+func pageInt(page map[string]string, name string, def, min, max int) (int, error) {
+	s, ok := page[name]
+	if !ok {
+		return def, nil
+	}
+	param := ParamPage + "[" + name + "]"
+	i, err := strconv.Atoi(s)
+	if err != nil || i < min {
+		return 0, &Error{Parameter: param, Detail: fmt.Sprintf("must be an integer greater than or equal to %d", min)}
+	}
+	if max > 0 && i > max {
+		return 0, &Error{Parameter: param, Detail: fmt.Sprintf("must not be greater than %d", max)}
+	}
+	return i, nil
+}
+
+// pageLink returns `u` with its `page[NAME]` query parameters replaced by `page`
+func pageLink(u *url.URL, page map[string]string) string {
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(key, ParamPage+"[") {
+			query.Del(key)
+		}
+	}
+	for name, value := range page {
+		query.Set(ParamPage+"["+name+"]", value)
+	}
+	link := *u
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,235 @@
+package queryparams_test
+
+import (
+	"encoding/json"
+	"github.com/fjgal/go-chi-jsonapi/queryparams"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestStrategy_Parse(t *testing.T) {
+	tests := []struct {
+		name              string
+		strategy          queryparams.Strategy
+		page              map[string]string
+		expectedPage      *queryparams.Page
+		expectedParameter string
+	}{
+		{
+			name:     "page-based defaults",
+			strategy: queryparams.PageNumber{},
+			page:     map[string]string{},
+			expectedPage: &queryparams.Page{
+				Number: 1, Size: queryparams.DefaultPageSize, Offset: 0, Limit: queryparams.DefaultPageSize,
+			},
+		},
+		{
+			name:         "page-based",
+			strategy:     queryparams.PageNumber{DefaultSize: 10, MaxSize: 50},
+			page:         map[string]string{"number": "3", "size": "25"},
+			expectedPage: &queryparams.Page{Number: 3, Size: 25, Offset: 50, Limit: 25},
+		},
+		{
+			name:              "page-based size above maximum",
+			strategy:          queryparams.PageNumber{MaxSize: 50},
+			page:              map[string]string{"size": "51"},
+			expectedParameter: "page[size]",
+		},
+		{
+			name:              "page-based invalid number",
+			strategy:          queryparams.PageNumber{},
+			page:              map[string]string{"number": "0"},
+			expectedParameter: "page[number]",
+		},
+		{
+			name:              "page-based number overflowing the next offset",
+			strategy:          queryparams.PageNumber{},
+			page:              map[string]string{"number": "922337203685477581", "size": "10"},
+			expectedParameter: "page[number]",
+		},
+		{
+			name:              "page-based unsupported parameter",
+			strategy:          queryparams.PageNumber{},
+			page:              map[string]string{"offset": "10"},
+			expectedParameter: "page[offset]",
+		},
+		{
+			name:         "offset-based",
+			strategy:     queryparams.OffsetLimit{DefaultLimit: 10},
+			page:         map[string]string{"offset": "30"},
+			expectedPage: &queryparams.Page{Offset: 30, Limit: 10},
+		},
+		{
+			name:              "offset-based invalid offset",
+			strategy:          queryparams.OffsetLimit{},
+			page:              map[string]string{"offset": "-1"},
+			expectedParameter: "page[offset]",
+		},
+		{
+			name:              "offset-based offset overflowing the next offset",
+			strategy:          queryparams.OffsetLimit{},
+			page:              map[string]string{"offset": "9223372036854775807", "limit": "10"},
+			expectedParameter: "page[offset]",
+		},
+		{
+			name:         "cursor-based",
+			strategy:     queryparams.Cursor{DefaultSize: 5},
+			page:         map[string]string{"after": "abc"},
+			expectedPage: &queryparams.Page{Size: 5, Limit: 5, After: "abc"},
+		},
+		{
+			name:              "cursor-based limit above maximum",
+			strategy:          queryparams.Cursor{MaxSize: 5},
+			page:              map[string]string{"size": "6"},
+			expectedParameter: "page[size]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := test.strategy.Parse(test.page)
+			if test.expectedParameter != "" {
+				if assert.IsType(t, &queryparams.Error{}, err) {
+					assert.Equal(t, test.expectedParameter, err.(*queryparams.Error).Parameter)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expectedPage.Number, page.Number)
+				assert.Equal(t, test.expectedPage.Size, page.Size)
+				assert.Equal(t, test.expectedPage.Offset, page.Offset)
+				assert.Equal(t, test.expectedPage.Limit, page.Limit)
+				assert.Equal(t, test.expectedPage.After, page.After)
+				assert.Equal(t, test.expectedPage.Before, page.Before)
+			}
+		})
+	}
+}
+
+// paginationLinks returns the links of a rendered document with unescaped query strings
+func paginationLinks(t *testing.T, body []byte) map[string]string {
+	var doc struct {
+		Links map[string]string `json:"links"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	for k, v := range doc.Links {
+		link, err := url.QueryUnescape(v)
+		assert.NoError(t, err)
+		doc.Links[k] = link
+	}
+	return doc.Links
+}
+
+func TestPaginate(t *testing.T) {
+	posts := func(n int) []*Post {
+		posts := []*Post{}
+		for i := 0; i < n; i++ {
+			posts = append(posts, &Post{ID: i + 1})
+		}
+		return posts
+	}
+
+	tests := []struct {
+		name          string
+		strategy      queryparams.Strategy
+		url           string
+		handle        func(page *queryparams.Page)
+		count         int
+		expectedLinks map[string]string
+	}{
+		{
+			name:     "page-based with total",
+			strategy: queryparams.PageNumber{DefaultSize: 10},
+			url:      "/blogs?sort=title&page[number]=2&page[size]=10",
+			handle:   func(page *queryparams.Page) { page.SetTotal(35) },
+			count:    10,
+			expectedLinks: map[string]string{
+				"first": "/blogs?page[number]=1&page[size]=10&sort=title",
+				"prev":  "/blogs?page[number]=1&page[size]=10&sort=title",
+				"next":  "/blogs?page[number]=3&page[size]=10&sort=title",
+				"last":  "/blogs?page[number]=4&page[size]=10&sort=title",
+			},
+		},
+		{
+			name:     "page-based last page without total",
+			strategy: queryparams.PageNumber{DefaultSize: 10},
+			url:      "/blogs",
+			count:    3,
+			expectedLinks: map[string]string{
+				"first": "/blogs?page[number]=1&page[size]=10",
+			},
+		},
+		{
+			name:     "offset-based with total",
+			strategy: queryparams.OffsetLimit{DefaultLimit: 10},
+			url:      "/blogs?page[offset]=5&filter[title]=foo",
+			handle:   func(page *queryparams.Page) { page.SetTotal(25) },
+			count:    10,
+			expectedLinks: map[string]string{
+				"first": "/blogs?filter[title]=foo&page[limit]=10&page[offset]=0",
+				"prev":  "/blogs?filter[title]=foo&page[limit]=10&page[offset]=0",
+				"next":  "/blogs?filter[title]=foo&page[limit]=10&page[offset]=15",
+				"last":  "/blogs?filter[title]=foo&page[limit]=10&page[offset]=20",
+			},
+		},
+		{
+			name:     "cursor-based",
+			strategy: queryparams.Cursor{DefaultSize: 2},
+			url:      "/blogs?page[after]=a",
+			handle: func(page *queryparams.Page) {
+				page.PrevCursor, page.NextCursor = "b", "c"
+			},
+			count: 2,
+			expectedLinks: map[string]string{
+				"first": "/blogs?page[size]=2",
+				"prev":  "/blogs?page[before]=b&page[size]=2",
+				"next":  "/blogs?page[after]=c&page[size]=2",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.handle != nil {
+					test.handle(queryparams.GetPage(r.Context()))
+				}
+				render.JSONAPI(w, r, posts(test.count))
+			})
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			w := httptest.NewRecorder()
+			queryparams.Middleware(queryparams.Paginate(test.strategy)(next)).ServeHTTP(w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, test.expectedLinks, paginationLinks(t, w.Body.Bytes()))
+		})
+	}
+
+	t.Run("should not add links to single payloads", func(t *testing.T) {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			render.JSONAPI(w, r, &Post{ID: 1})
+		})
+		r := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+		w := httptest.NewRecorder()
+		queryparams.Paginate(queryparams.PageNumber{})(next).ServeHTTP(w, r)
+		assert.NotContains(t, w.Body.String(), "links")
+	})
+
+	t.Run("should respond 400 on invalid page parameters", func(t *testing.T) {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("next handler should not be called")
+		})
+		r := httptest.NewRequest(http.MethodGet, "/blogs?page[size]=1000", nil)
+		w := httptest.NewRecorder()
+		queryparams.Paginate(queryparams.PageNumber{MaxSize: 100})(next).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"invalid query parameter \"page[size]\": must not be greater than 100","status":"400","code":"invalid_query_parameter","source":{"parameter":"page[size]"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+}
@@ -0,0 +1,213 @@
+// Package queryparams parses JSON:API query parameters (`include`, `fields`, `sort`, `page` and `filter`)
+// see https://jsonapi.org/format/#query-parameters
+package queryparams
+
+import (
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	ParamInclude = "include"
+	ParamFields  = "fields"
+	ParamSort    = "sort"
+	ParamPage    = "page"
+	ParamFilter  = "filter"
+)
+
+// Params holds the JSON:API query parameters of a request
+type Params struct {
+	// Include holds the relationship paths given in `include`, e.g. `posts.comments`
+	Include []string
+	// Fields holds the sparse fieldsets given as `fields[TYPE]`, keyed by resource type
+	Fields map[string][]string
+	// Sort holds the sort fields given in `sort`, in order of precedence
+	Sort []SortField
+	// Page holds the pagination parameters given as `page[NAME]`, keyed by NAME
+	Page map[string]string
+	// Filter holds the filters given as `filter[FIELD]` or `filter[FIELD][OPERATOR]`
+	Filter []Filter
+}
+
+// SortField is a single sort criteria
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// Filter is a single filter criteria, Operator is empty when not given (`filter[FIELD]`) which means `eq`
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// Error is returned when a query parameter is malformed
+type Error struct {
+	// Parameter is the offending query parameter, e.g. `fields[blogs]`
+	Parameter string
+	Detail    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query parameter %q: %s", e.Parameter, e.Detail)
+}
+
+// JSONAPIError implements render.ErrorObjecter
+func (e *Error) JSONAPIError() *render.Error {
+	return &render.Error{
+		Status: http.StatusBadRequest,
+		Code:   "invalid_query_parameter",
+		Detail: e.Error(),
+		Source: &render.ErrorSource{Parameter: e.Parameter},
+	}
+}
+
+// Parse parses the JSON:API query parameters from `query`
+// parameters not belonging to a JSON:API family are ignored
+func Parse(query url.Values) (*Params, error) {
+	params := &Params{
+		Fields: map[string][]string{},
+		Page:   map[string]string{},
+	}
+
+	// iterate in a stable order so that errors and filters are deterministic
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		family, members, err := parseKey(key)
+		switch family {
+		case ParamInclude, ParamFields, ParamSort, ParamPage, ParamFilter:
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(query[key]) > 1 {
+			return nil, &Error{Parameter: key, Detail: "must be given only once"}
+		}
+		value := query[key][0]
+
+		switch family {
+		case ParamInclude:
+			if len(members) != 0 {
+				return nil, &Error{Parameter: key, Detail: "must not have members"}
+			}
+			if params.Include, err = parseInclude(key, value); err != nil {
+				return nil, err
+			}
+		case ParamFields:
+			if len(members) != 1 {
+				return nil, &Error{Parameter: key, Detail: "must be given as fields[TYPE]"}
+			}
+			if params.Fields[members[0]], err = parseList(key, value); err != nil {
+				return nil, err
+			}
+		case ParamSort:
+			if len(members) != 0 {
+				return nil, &Error{Parameter: key, Detail: "must not have members"}
+			}
+			if params.Sort, err = parseSort(key, value); err != nil {
+				return nil, err
+			}
+		case ParamPage:
+			if len(members) != 1 {
+				return nil, &Error{Parameter: key, Detail: "must be given as page[NAME]"}
+			}
+			params.Page[members[0]] = value
+		case ParamFilter:
+			filter := Filter{Value: value}
+			switch len(members) {
+			case 2:
+				filter.Operator = members[1]
+				fallthrough
+			case 1:
+				filter.Field = members[0]
+			default:
+				return nil, &Error{Parameter: key, Detail: "must be given as filter[FIELD] or filter[FIELD][OPERATOR]"}
+			}
+			params.Filter = append(params.Filter, filter)
+		}
+	}
+
+	return params, nil
+}
+
+// parseKey splits a query parameter name such as `filter[title][eq]` into its family and members,
+// the family is returned even when the members are malformed
+func parseKey(key string) (family string, members []string, err error) {
+	i := strings.Index(key, "[")
+	if i < 0 {
+		return key, nil, nil
+	}
+	family, rest := key[:i], key[i:]
+	for rest != "" {
+		end := strings.Index(rest, "]")
+		if rest[0] != '[' || end < 0 {
+			return family, nil, &Error{Parameter: key, Detail: "unbalanced brackets"}
+		}
+		member := rest[1:end]
+		if member == "" || strings.Contains(member, "[") {
+			return family, nil, &Error{Parameter: key, Detail: "empty or nested member name"}
+		}
+		members = append(members, member)
+		rest = rest[end+1:]
+	}
+	return family, members, nil
+}
+
+// parseList parses a comma separated list, an empty value yields an empty list
+func parseList(key, value string) ([]string, error) {
+	list := []string{}
+	if value == "" {
+		return list, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		if item == "" {
+			return nil, &Error{Parameter: key, Detail: "must not contain empty values"}
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func parseInclude(key, value string) ([]string, error) {
+	paths, err := parseList(key, value)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" {
+				return nil, &Error{Parameter: key, Detail: fmt.Sprintf("invalid relationship path %q", path)}
+			}
+		}
+	}
+	return paths, nil
+}
+
+func parseSort(key, value string) ([]SortField, error) {
+	fields, err := parseList(key, value)
+	if err != nil {
+		return nil, err
+	}
+	var sortFields []SortField
+	for _, field := range fields {
+		sortField := SortField{Field: strings.TrimPrefix(field, "-"), Descending: strings.HasPrefix(field, "-")}
+		if sortField.Field == "" {
+			return nil, &Error{Parameter: key, Detail: fmt.Sprintf("invalid sort field %q", field)}
+		}
+		sortFields = append(sortFields, sortField)
+	}
+	return sortFields, nil
+}
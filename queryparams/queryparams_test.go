@@ -0,0 +1,99 @@
+package queryparams_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/queryparams"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedParams *queryparams.Params
+	}{
+		{
+			name:  "no parameters",
+			query: "",
+			expectedParams: &queryparams.Params{
+				Fields: map[string][]string{},
+				Page:   map[string]string{},
+			},
+		},
+		{
+			name:  "every family",
+			query: "include=posts.comments,current_post&fields[blogs]=title,posts&fields[posts]=&sort=-created_at,title&page[number]=2&page[size]=10&filter[title]=foo&filter[view_count][gt]=10",
+			expectedParams: &queryparams.Params{
+				Include: []string{"posts.comments", "current_post"},
+				Fields: map[string][]string{
+					"blogs": {"title", "posts"},
+					"posts": {},
+				},
+				Sort: []queryparams.SortField{
+					{Field: "created_at", Descending: true},
+					{Field: "title"},
+				},
+				Page: map[string]string{"number": "2", "size": "10"},
+				Filter: []queryparams.Filter{
+					{Field: "title", Value: "foo"},
+					{Field: "view_count", Operator: "gt", Value: "10"},
+				},
+			},
+		},
+		{
+			name:  "ignores implementation specific parameters",
+			query: "camelCase=1&q[x]=2&q[]=1&foo[=2&bar[a][[b]=3",
+			expectedParams: &queryparams.Params{
+				Fields: map[string][]string{},
+				Page:   map[string]string{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			params, err := queryparams.Parse(query)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name              string
+		query             string
+		expectedParameter string
+	}{
+		{name: "fields without type", query: "fields=title", expectedParameter: "fields"},
+		{name: "fields with empty field", query: "fields[blogs]=title,,posts", expectedParameter: "fields[blogs]"},
+		{name: "include with members", query: "include[blogs]=posts", expectedParameter: "include[blogs]"},
+		{name: "include with empty path segment", query: "include=posts..comments", expectedParameter: "include"},
+		{name: "sort with empty field", query: "sort=-", expectedParameter: "sort"},
+		{name: "page without name", query: "page=1", expectedParameter: "page"},
+		{name: "filter with too many members", query: "filter[a][b][c]=1", expectedParameter: "filter[a][b][c]"},
+		{name: "unbalanced brackets", query: "filter[title=foo", expectedParameter: "filter[title"},
+		{name: "empty member", query: "page[]=1", expectedParameter: "page[]"},
+		{name: "given twice", query: "sort=title&sort=created_at", expectedParameter: "sort"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			_, err = queryparams.Parse(query)
+			if assert.IsType(t, &queryparams.Error{}, err) {
+				assert.Equal(t, test.expectedParameter, err.(*queryparams.Error).Parameter)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package queryparams
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ErrNotResource is returned when not given a struct pointer, or a slice of struct pointers, annotated with jsonapi tags
+var ErrNotResource = errors.New("expected a struct pointer or a slice of struct pointers annotated with jsonapi tags")
+
+// ValidateSort returns a 400 Bad Request error for each sort field that is not an `attr` of `model`,
+// a struct pointer or a slice of struct pointers, or that is not one of `allowed` when given
+func ValidateSort(fields []SortField, model interface{}, allowed ...string) error {
+	res := resource.Of(reflect.TypeOf(model))
+	if res == nil {
+		return ErrNotResource
+	}
+	var errs render.Errors
+	for _, f := range fields {
+		if res.Attribute(f.Field) == nil || (len(allowed) > 0 && !contains(allowed, f.Field)) {
+			errs = append(errs, &Error{Parameter: ParamSort, Detail: fmt.Sprintf("sorting by %q is not allowed", f.Field)})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SortSlice sorts `slice`, a slice of struct pointers, in place by the attributes of `fields`
+// attributes must be strings, booleans, numbers or times (or pointers to them, nil first)
+func SortSlice(slice interface{}, fields []SortField) error {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Ptr {
+		return ErrNotResource
+	}
+	res := resource.Of(v.Type())
+	if res == nil {
+		return ErrNotResource
+	}
+
+	attrs := make([]*resource.Field, len(fields))
+	for i, f := range fields {
+		if attrs[i] = res.Attribute(f.Field); attrs[i] == nil {
+			return &Error{Parameter: ParamSort, Detail: fmt.Sprintf("sorting by %q is not allowed", f.Field)}
+		}
+		if !sortable(attrs[i].Type) {
+			return fmt.Errorf("cannot sort by %q of type %s", f.Field, attrs[i].Type)
+		}
+	}
+
+	sort.SliceStable(slice, func(i, j int) bool {
+		a, b := v.Index(i).Elem(), v.Index(j).Elem()
+		for k, f := range fields {
+			c := compare(a.Field(attrs[k].Index), b.Field(attrs[k].Index))
+			if c == 0 {
+				continue
+			}
+			if f.Descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func sortable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// compare returns -1, 0 or 1 as `a` is less than, equal to or greater than `b`, both of a sortable type
+func compare(a, b reflect.Value) int {
+	switch {
+	case less(a, b):
+		return -1
+	case less(b, a):
+		return 1
+	}
+	return 0
+}
+
+func less(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Ptr {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && !b.IsNil()
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Before(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	default:
+		return a.Float() < b.Float()
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
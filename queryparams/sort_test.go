@@ -0,0 +1,93 @@
+package queryparams_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/queryparams"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Blog struct {
+	ID        int        `jsonapi:"primary,blogs"`
+	Title     string     `jsonapi:"attr,title"`
+	CreatedAt time.Time  `jsonapi:"attr,created_at"`
+	ViewCount int        `jsonapi:"attr,view_count"`
+	Rating    *float64   `jsonapi:"attr,rating"`
+	Tags      []string   `jsonapi:"attr,tags"`
+	Posts     []*Post    `jsonapi:"relation,posts"`
+	UpdatedAt *time.Time `jsonapi:"attr,updated_at"`
+}
+
+type Post struct {
+	ID    int    `jsonapi:"primary,posts"`
+	Title string `jsonapi:"attr,title"`
+}
+
+func TestValidateSort(t *testing.T) {
+
+	t.Run("should accept attributes", func(t *testing.T) {
+		fields := []queryparams.SortField{{Field: "created_at", Descending: true}, {Field: "title"}}
+		assert.NoError(t, queryparams.ValidateSort(fields, &Blog{}))
+		assert.NoError(t, queryparams.ValidateSort(fields, []*Blog{}))
+	})
+
+	t.Run("should respond 400 on fields not allowed", func(t *testing.T) {
+		fields := []queryparams.SortField{{Field: "posts"}, {Field: "title"}, {Field: "view_count"}}
+		err := queryparams.ValidateSort(fields, &Blog{}, "title", "created_at")
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs?sort=posts,title,view_count", nil)
+		w := httptest.NewRecorder()
+		render.JSONAPI(w, r, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"invalid query parameter \"sort\": sorting by \"posts\" is not allowed","status":"400","code":"invalid_query_parameter","source":{"parameter":"sort"}},{"title":"Bad Request","detail":"invalid query parameter \"sort\": sorting by \"view_count\" is not allowed","status":"400","code":"invalid_query_parameter","source":{"parameter":"sort"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should fail on non resources", func(t *testing.T) {
+		assert.Equal(t, queryparams.ErrNotResource, queryparams.ValidateSort(nil, &struct{}{}))
+	})
+}
+
+func TestSortSlice(t *testing.T) {
+	now := time.Now()
+	low, high := 1.5, 4.5
+	blogs := []*Blog{
+		{ID: 1, Title: "b", CreatedAt: now, ViewCount: 3, Rating: &high},
+		{ID: 2, Title: "a", CreatedAt: now.Add(time.Hour), ViewCount: 3},
+		{ID: 3, Title: "c", CreatedAt: now.Add(-time.Hour), ViewCount: 1, Rating: &low},
+	}
+
+	ids := func(blogs []*Blog) (ids []int) {
+		for _, b := range blogs {
+			ids = append(ids, b.ID)
+		}
+		return
+	}
+
+	tests := []struct {
+		name        string
+		fields      []queryparams.SortField
+		expectedIDs []int
+	}{
+		{name: "string ascending", fields: []queryparams.SortField{{Field: "title"}}, expectedIDs: []int{2, 1, 3}},
+		{name: "time descending", fields: []queryparams.SortField{{Field: "created_at", Descending: true}}, expectedIDs: []int{2, 1, 3}},
+		{name: "multiple fields", fields: []queryparams.SortField{{Field: "view_count", Descending: true}, {Field: "title"}}, expectedIDs: []int{2, 1, 3}},
+		{name: "pointers with nil first", fields: []queryparams.SortField{{Field: "rating"}}, expectedIDs: []int{2, 3, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorted := append([]*Blog{}, blogs...)
+			assert.NoError(t, queryparams.SortSlice(sorted, test.fields))
+			assert.Equal(t, test.expectedIDs, ids(sorted))
+		})
+	}
+
+	t.Run("should fail on unknown or unsortable attributes", func(t *testing.T) {
+		assert.IsType(t, &queryparams.Error{}, queryparams.SortSlice(blogs, []queryparams.SortField{{Field: "titel"}}))
+		assert.EqualError(t, queryparams.SortSlice(blogs, []queryparams.SortField{{Field: "tags"}}), `cannot sort by "tags" of type []string`)
+		assert.Equal(t, queryparams.ErrNotResource, queryparams.SortSlice(Blog{}, nil))
+	})
+}
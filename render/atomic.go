@@ -0,0 +1,486 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	chi_render "github.com/go-chi/render"
+	"github.com/google/jsonapi"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// AtomicExtension is the URI of the Atomic Operations extension, see https://jsonapi.org/ext/atomic/
+// it must be registered in Extensions to be negotiated
+const AtomicExtension = "https://jsonapi.org/ext/atomic"
+
+// Atomic operation codes
+const (
+	OpAdd    = "add"
+	OpUpdate = "update"
+	OpRemove = "remove"
+)
+
+// ErrAtomicMediaType is responded when atomic operations are not sent with the Atomic Operations extension media type
+var ErrAtomicMediaType = errors.New(`atomic operations must be sent with the media type application/vnd.api+json;ext="https://jsonapi.org/ext/atomic"`)
+
+// Operation is an atomic operation, see https://jsonapi.org/ext/atomic/#operation-objects
+// Ref is filled in from Href when the operation targets a URL, see DecodeOperations
+type Operation struct {
+	Op   string                 `json:"op"`
+	Ref  *OperationRef          `json:"ref,omitempty"`
+	Href string                 `json:"href,omitempty"`
+	Data json.RawMessage        `json:"data,omitempty"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+	// Index is the position of the operation in the `atomic:operations` array
+	Index int `json:"-"`
+
+	lids      LocalIDs
+	clientIDs *ClientIDPolicy
+	strict    bool
+}
+
+// OperationRef identifies the target of an operation, a resource or one of its relationships
+type OperationRef struct {
+	Type         string `json:"type"`
+	ID           string `json:"id,omitempty"`
+	LID          string `json:"lid,omitempty"`
+	Relationship string `json:"relationship,omitempty"`
+}
+
+// OperationResult is the result of an operation, Data is a struct pointer (or nil).
+// Results are rendered once every operation succeeded, Data must not be modified by later operations
+type OperationResult struct {
+	Data interface{}
+	Meta map[string]interface{}
+
+	// lid is the local id of the resource added by the operation
+	lid string
+}
+
+// AtomicResults holds the results of atomic operations, JSONAPI renders them in an `atomic:results` document
+// or responds 204 No Content when none of them has data or meta
+type AtomicResults []*OperationResult
+
+// OperationHandler performs an operation, `ctx` is the one given by AtomicProcessor.Transaction
+type OperationHandler func(ctx context.Context, op *Operation) (*OperationResult, error)
+
+// TransactionFunc runs `fn`, e.g. within a database transaction that is rolled back when `fn` returns an error
+type TransactionFunc func(ctx context.Context, fn func(ctx context.Context) error) error
+
+// AtomicProcessor dispatches atomic operations to the handlers registered for their resource type
+type AtomicProcessor struct {
+	// Transaction runs all the operations of a request, operations run without a transaction when nil
+	Transaction TransactionFunc
+	// BasePath is the path of the API root, e.g. `/api/v1`, stripped from `href` before resolving it
+	BasePath string
+
+	handlers map[string]OperationHandler
+}
+
+// Handle registers the handler of the operations on resources of type `typ`
+func (p *AtomicProcessor) Handle(typ string, h OperationHandler) {
+	if p.handlers == nil {
+		p.handlers = map[string]OperationHandler{}
+	}
+	p.handlers[typ] = h
+}
+
+// ServeHTTP decodes the atomic operations of the request, processes them and renders their results
+func (p *AtomicProcessor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !hasAtomicExtension(r) {
+		chi_render.Status(r, http.StatusUnsupportedMediaType)
+		JSONAPI(w, r, ErrAtomicMediaType)
+		return
+	}
+	ops, err := decodeOperations(r.Body, p.BasePath)
+	if err != nil {
+		JSONAPI(w, r, err)
+		return
+	}
+	results, err := p.Process(r.Context(), ops)
+	if err != nil {
+		JSONAPI(w, r, err)
+		return
+	}
+	JSONAPI(w, r, results)
+}
+
+// Process runs the handlers of `ops` in order within Transaction, it stops at the first failing operation
+// and returns its errors with their `source.pointer` indexing into the operations array.
+// `lid` of the resources added by previous operations are resolved in `ref`, see Operation.LocalID,
+// and the ClientIDPolicy of `ctx`, if any, is applied to the resources added, see Operation.Decode
+func (p *AtomicProcessor) Process(ctx context.Context, ops []*Operation) (AtomicResults, error) {
+	clientIDs, strict := getClientIDPolicy(ctx), isStrict(ctx)
+	var results AtomicResults
+	run := func(ctx context.Context) error {
+		results = make(AtomicResults, 0, len(ops))
+		lids := LocalIDs{}
+		for _, op := range ops {
+			op.clientIDs, op.strict = clientIDs, strict
+			result, err := p.process(ctx, op, lids)
+			if err != nil {
+				return operationErrors(op.Index, err)
+			}
+			if result == nil {
+				result = &OperationResult{}
+			}
+			results = append(results, result)
+		}
+		return nil
+	}
+
+	var err error
+	if p.Transaction != nil {
+		err = p.Transaction(ctx, run)
+	} else {
+		err = run(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (p *AtomicProcessor) process(ctx context.Context, op *Operation, lids LocalIDs) (*OperationResult, error) {
+	op.lids = lids
+	if op.Ref != nil && op.Ref.LID != "" {
+		id, ok := op.LocalID(op.Ref.Type, op.Ref.LID)
+		if !ok {
+			return nil, newOperationError("/ref/lid", fmt.Sprintf("unknown local id %q", op.Ref.LID))
+		}
+		op.Ref.ID = id
+	}
+
+	typ := op.Type()
+	handler, ok := p.handlers[typ]
+	if !ok {
+		pointer := "/data/type"
+		switch {
+		case op.Href != "":
+			pointer = "/href"
+		case op.Ref != nil:
+			pointer = "/ref/type"
+		}
+		return nil, newOperationError(pointer, fmt.Sprintf("unsupported resource type %q", typ))
+	}
+	result, err := handler(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+
+	// map the local id of an added resource to the id it was given
+	if _, _, lid := op.identifier(); op.Op == OpAdd && lid != "" && result != nil {
+		if res := resource.Of(reflect.TypeOf(result.Data)); res != nil && res.Name == typ {
+			lids.Set(typ, lid, primaryID(res, reflect.ValueOf(result.Data)))
+			result.lid = lid
+		}
+	}
+	return result, nil
+}
+
+// Type returns the resource type of the operation, from `ref` or `data`
+func (op *Operation) Type() string {
+	if op.Ref != nil {
+		return op.Ref.Type
+	}
+	typ, _, _ := op.identifier()
+	return typ
+}
+
+// LocalID returns the id given to the resource of type `typ` added with the local id `lid` by a previous operation
+func (op *Operation) LocalID(typ, lid string) (string, bool) {
+	return op.lids.Get(typ, lid)
+}
+
+// Decode decodes the resource object of the operation `data` into `v`, a struct pointer, see DecodeJSONAPI.
+// Local ids of resources added by previous operations are resolved and the client-generated id of
+// the resource of an `add` operation is checked against the policy set with ClientIDs.
+// Unknown members are reported when the request is decoded strictly, see Strict.
+// Errors point to `/data`, Process prefixes them with the position of the operation
+func (op *Operation) Decode(v interface{}) error {
+	_, err := op.DecodePartial(v)
+	return err
+}
+
+// DecodePartial decodes the resource object of the operation like Decode and returns the attributes
+// and relationships present in it, e.g. to apply an `update` operation with ApplyPresent
+func (op *Operation) DecodePartial(v interface{}) (*PresentFields, error) {
+	body := &bytes.Buffer{}
+	body.WriteString(`{"data":`)
+	body.Write(op.Data)
+	body.WriteString("}")
+	opts := decodeOptions{lids: op.lids, strict: op.strict}
+	if op.Op == OpAdd && (op.Ref == nil || op.Ref.Relationship == "") {
+		opts.clientIDs = op.clientIDs
+	}
+	present, err := decodeJSONAPI(body, v, opts)
+	if _, ok := asErrorObject(err); err != nil && !ok {
+		return nil, newOperationError("/data", err.Error())
+	}
+	return present, err
+}
+
+// identifier returns the `type`, `id` and `lid` of the resource object of the operation `data`, if any
+func (op *Operation) identifier() (typ, id, lid string) {
+	var identifier struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		LID  string `json:"lid"`
+	}
+	_ = json.Unmarshal(op.Data, &identifier)
+	return identifier.Type, identifier.ID, identifier.LID
+}
+
+// DecodeOperations decodes an Atomic Operations document, a 400 Bad Request error is returned for
+// each invalid operation with a `source.pointer` indexing into the operations array.
+// `href` is resolved relative to the API root into Ref, see parseHref
+func DecodeOperations(r io.Reader) ([]*Operation, error) {
+	return decodeOperations(r, "")
+}
+
+// decodeOperations decodes an Atomic Operations document whose `href` are relative to `basePath`
+func decodeOperations(r io.Reader, basePath string) ([]*Operation, error) {
+	defer io.Copy(ioutil.Discard, r)
+
+	var doc struct {
+		Operations *[]*Operation `json:"atomic:operations"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, newOperationError("", "request body is not a valid Atomic Operations document")
+	}
+	if doc.Operations == nil {
+		return nil, newOperationError("", "missing `atomic:operations` member")
+	}
+
+	var errs Errors
+	for i, op := range *doc.Operations {
+		if op == nil {
+			errs = append(errs, operationErrors(i, newOperationError("", "expected an operation object")))
+			continue
+		}
+		op.Index = i
+		if err := validateOperation(op, basePath); err != nil {
+			errs = append(errs, operationErrors(i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return *doc.Operations, nil
+}
+
+func validateOperation(op *Operation, basePath string) error {
+	switch op.Op {
+	case OpAdd, OpUpdate, OpRemove:
+	default:
+		return newOperationError("/op", fmt.Sprintf("unknown operation %q, expected one of add, update or remove", op.Op))
+	}
+	if op.Ref != nil && op.Href != "" {
+		return newOperationError("", "`ref` and `href` must not be given together")
+	}
+	at := func(member string) string {
+		return "/ref" + member
+	}
+	if op.Href != "" {
+		ref, err := parseHref(op.Href, basePath)
+		if err != nil {
+			return err
+		}
+		op.Ref = ref
+		at = func(string) string {
+			return "/href"
+		}
+	}
+	if op.Ref != nil {
+		switch {
+		case op.Ref.Type == "":
+			return newOperationError(at("/type"), "missing resource type")
+		case op.Ref.ID != "" && op.Ref.LID != "":
+			return newOperationError(at(""), "`id` and `lid` must not be given together")
+		case op.Ref.ID == "" && op.Ref.LID == "" && (op.Op != OpAdd || op.Ref.Relationship != ""):
+			return newOperationError(at("/id"), "missing resource id")
+		}
+	}
+	relationship := op.Ref != nil && op.Ref.Relationship != ""
+	if op.Op == OpRemove && !relationship && op.Ref == nil {
+		return newOperationError("", "remove operations must have a `ref` or an `href`")
+	}
+	if op.Data == nil && (op.Op != OpRemove || relationship) {
+		return newOperationError("/data", "missing `data` member")
+	}
+	if op.Type() == "" {
+		if op.Data == nil {
+			return newOperationError("", "cannot determine the resource type without `ref` nor `data`")
+		}
+		return newOperationError("/data/type", "missing resource type")
+	}
+	return nil
+}
+
+// parseHref returns the target of an operation `href`, relative to the API root `basePath`:
+// `/TYPE`, `/TYPE/ID` or `/TYPE/ID/relationships/NAME`, only the path of absolute URLs is considered
+func parseHref(href, basePath string) (*OperationRef, error) {
+	root := strings.TrimRight(basePath, "/")
+	invalid := newOperationError("/href", fmt.Sprintf("invalid `href` %q, expected %[2]s/TYPE, %[2]s/TYPE/ID or %[2]s/TYPE/ID/relationships/NAME", href, root))
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, invalid
+	}
+	path := u.Path
+	if root != "" {
+		if path != root && !strings.HasPrefix(path, root+"/") {
+			return nil, invalid
+		}
+		path = strings.TrimPrefix(path, root)
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, invalid
+		}
+	}
+	switch {
+	case len(segments) == 1:
+		return &OperationRef{Type: segments[0]}, nil
+	case len(segments) == 2:
+		return &OperationRef{Type: segments[0], ID: segments[1]}, nil
+	case len(segments) == 4 && segments[2] == "relationships":
+		return &OperationRef{Type: segments[0], ID: segments[1], Relationship: segments[3]}, nil
+	}
+	return nil, invalid
+}
+
+// renderResults renders the `atomic:results` document of `results`
+func renderResults(w http.ResponseWriter, r *http.Request, results AtomicResults) {
+	params := GetMediaTypeParams(r)
+	if !params.HasExt(AtomicExtension) {
+		params.Ext = append(append([]string{}, params.Ext...), AtomicExtension)
+	}
+	w.Header().Set("Content-Type", params.MediaType())
+
+	type result struct {
+		Data interface{}            `json:"data,omitempty"`
+		Meta map[string]interface{} `json:"meta,omitempty"`
+	}
+	doc := &struct {
+		Results []*result      `json:"atomic:results"`
+		Meta    *jsonapi.Meta  `json:"meta,omitempty"`
+		JSONAPI *JSONAPIObject `json:"jsonapi,omitempty"`
+	}{Results: make([]*result, 0, len(results))}
+
+	empty := true
+	for _, res := range results {
+		rendered := &result{Meta: res.Meta}
+		if res.Data != nil && reflect.ValueOf(res.Data).Kind() != reflect.Ptr {
+			err := fmt.Errorf("operation result data must be a struct pointer, got %T", res.Data)
+			writeErrors(w, r, http.StatusInternalServerError, false, err)
+			return
+		}
+		if res.Data != nil && !reflect.ValueOf(res.Data).IsNil() {
+			payload, err := jsonapi.Marshal(res.Data)
+			if err != nil {
+				errs := flattenErrors(err)
+				writeErrors(w, r, errorsStatus(errs), false, errs...)
+				return
+			}
+			if one, ok := payload.(*jsonapi.OnePayload); ok && one.Data != nil {
+				lids := localNodes(r, res.Data, &document{Data: one.Data})
+				if res.lid != "" {
+					lids = map[string]string{nodeKey(one.Data): res.lid}
+				}
+				rendered.Data = withLocalID(one.Data, lids)
+			}
+		}
+		empty = empty && rendered.Data == nil && len(rendered.Meta) == 0
+		doc.Results = append(doc.Results, rendered)
+	}
+
+	top := &document{}
+	top.setTopLevel(r, false, 0)
+	doc.Meta, doc.JSONAPI = top.Meta, top.JSONAPI
+
+	if empty && doc.Meta == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(doc); err != nil {
+		errs := flattenErrors(err)
+		writeErrors(w, r, errorsStatus(errs), false, errs...)
+		return
+	}
+	if status, ok := r.Context().Value(chi_render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// hasAtomicExtension reports whether the request Content-Type applies the Atomic Operations extension
+func hasAtomicExtension(r *http.Request) bool {
+	if GetRequestContentType(r) != ContentTypeJSONAPI {
+		return false
+	}
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && contains(strings.Fields(params["ext"]), AtomicExtension)
+}
+
+// operationError is an error of the operation at `index`, its error object points into the operations array
+type operationError struct {
+	index int
+	err   error
+}
+
+// operationErrors wraps each error of `err` as an error of the operation at `index`
+func operationErrors(index int, err error) error {
+	errs := flattenErrors(err)
+	if len(errs) == 1 {
+		return &operationError{index: index, err: errs[0]}
+	}
+	wrapped := make(Errors, len(errs))
+	for i, e := range errs {
+		wrapped[i] = &operationError{index: index, err: e}
+	}
+	return wrapped
+}
+
+func (e *operationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *operationError) Unwrap() error {
+	return e.err
+}
+
+// JSONAPIError implements ErrorObjecter, pointers relative to the operation are prefixed with its position
+func (e *operationError) JSONAPIError() *Error {
+	obj := toJSONAPIErrors(errorsStatus([]error{e.err}), false, e.err)[0]
+	prefix := "/atomic:operations/" + strconv.Itoa(e.index)
+	source := ErrorSource{}
+	if obj.Source != nil {
+		source = *obj.Source
+	}
+	if !strings.HasPrefix(source.Pointer, "/atomic:operations") && source.Parameter == "" && source.Header == "" {
+		source.Pointer = prefix + source.Pointer
+	}
+	obj.Source = &source
+	return obj
+}
+
+// newOperationError returns a 400 Bad Request error, `pointer` is relative to the operation
+func newOperationError(pointer, detail string) error {
+	err := &Error{Status: http.StatusBadRequest, Code: "invalid_operation", Detail: detail}
+	if pointer != "" {
+		err.Source = &ErrorSource{Pointer: pointer}
+	}
+	return err
+}
@@ -0,0 +1,252 @@
+package render_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const atomicMediaType = `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`
+
+// newAtomicProcessor returns a processor of posts operations recording whether its transactions are committed
+func newAtomicProcessor(committed *bool) *render.AtomicProcessor {
+	posts := map[string]*Post{}
+	p := &render.AtomicProcessor{
+		Transaction: func(ctx context.Context, fn func(ctx context.Context) error) error {
+			err := fn(ctx)
+			*committed = err == nil
+			return err
+		},
+	}
+	p.Handle("posts", func(ctx context.Context, op *render.Operation) (*render.OperationResult, error) {
+		switch op.Op {
+		case render.OpAdd:
+			post := &Post{}
+			if err := op.Decode(post); err != nil {
+				return nil, err
+			}
+			post.ID = len(posts) + 1
+			posts[strconv.Itoa(post.ID)] = post
+			return &render.OperationResult{Data: post}, nil
+		case render.OpUpdate:
+			stored, ok := posts[op.Ref.ID]
+			if !ok {
+				return nil, &render.Error{Status: http.StatusNotFound, Detail: "post not found"}
+			}
+			post := *stored
+			if err := op.Decode(&post); err != nil {
+				return nil, err
+			}
+			post.ID, _ = strconv.Atoi(op.Ref.ID)
+			posts[op.Ref.ID] = &post
+			return &render.OperationResult{Data: &post}, nil
+		default:
+			delete(posts, op.Ref.ID)
+			return nil, nil
+		}
+	})
+	return p
+}
+
+func TestAtomicProcessor(t *testing.T) {
+	tests := []struct {
+		name                string
+		contentType         string
+		body                string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+		expectedCommitted   bool
+	}{
+		{
+			name:                "add and update using a local id",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"add","data":{"type":"posts","lid":"a","attributes":{"title":"first"}}},{"op":"update","ref":{"type":"posts","lid":"a"},"data":{"type":"posts","lid":"a","attributes":{"title":"renamed"}}},{"op":"remove","ref":{"type":"posts","id":"9"}}]}`,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: atomicMediaType,
+			expectedBody:        `{"atomic:results":[{"data":{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":"first"},"relationships":{"comments":{"data":[]}},"lid":"a"}},{"data":{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":"renamed"},"relationships":{"comments":{"data":[]}}}},{}]}`,
+			expectedCommitted:   true,
+		},
+		{
+			name:                "update and remove using href",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"add","data":{"type":"posts","attributes":{"title":"first"}}},{"op":"update","href":"/posts/1","data":{"type":"posts","id":"1","attributes":{"title":"renamed"}}},{"op":"remove","href":"https://example.com/posts/1"}]}`,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: atomicMediaType,
+			expectedBody:        `{"atomic:results":[{"data":{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":"first"},"relationships":{"comments":{"data":[]}}}},{"data":{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":"renamed"},"relationships":{"comments":{"data":[]}}}},{}]}`,
+			expectedCommitted:   true,
+		},
+		{
+			name:              "no results",
+			contentType:       atomicMediaType,
+			body:              `{"atomic:operations":[{"op":"remove","ref":{"type":"posts","id":"1"}}]}`,
+			expectedStatus:    http.StatusNoContent,
+			expectedCommitted: true,
+		},
+		{
+			name:                "failing operation",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"add","data":{"type":"posts","attributes":{"title":"first"}}},{"op":"update","ref":{"type":"posts","id":"2"},"data":{"type":"posts","id":"2"}}]}`,
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/vnd.api+json",
+			expectedBody:        `{"errors":[{"title":"Not Found","detail":"post not found","status":"404","source":{"pointer":"/atomic:operations/1"}}]}`,
+		},
+		{
+			name:                "unknown local id",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"remove","ref":{"type":"posts","lid":"a"}}]}`,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/vnd.api+json",
+			expectedBody:        `{"errors":[{"title":"Bad Request","detail":"unknown local id \"a\"","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/0/ref/lid"}}]}`,
+		},
+		{
+			name:                "unsupported type",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"add","data":{"type":"blogs"}}]}`,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/vnd.api+json",
+			expectedBody:        `{"errors":[{"title":"Bad Request","detail":"unsupported resource type \"blogs\"","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/0/data/type"}}]}`,
+		},
+		{
+			name:                "invalid operations",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"add","data":{"type":"posts"}},{"op":"create"},{"op":"update","ref":{"type":"posts"},"data":{"type":"posts"}},{"op":"add"}]}`,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/vnd.api+json",
+			expectedBody:        `{"errors":[{"title":"Bad Request","detail":"unknown operation \"create\", expected one of add, update or remove","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/1/op"}},{"title":"Bad Request","detail":"missing resource id","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/2/ref/id"}},{"title":"Bad Request","detail":"missing ` + "`data`" + ` member","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/3/data"}}]}`,
+		},
+		{
+			name:                "invalid href",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"remove","href":"/posts/1/comments"},{"op":"remove","href":"/posts"},{"op":"remove","href":"/blogs/1"}]}`,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/vnd.api+json",
+			expectedBody:        `{"errors":[{"title":"Bad Request","detail":"invalid ` + "`href`" + ` \"/posts/1/comments\", expected /TYPE, /TYPE/ID or /TYPE/ID/relationships/NAME","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/0/href"}},{"title":"Bad Request","detail":"missing resource id","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/1/href"}}]}`,
+		},
+		{
+			name:                "unsupported href type",
+			contentType:         atomicMediaType,
+			body:                `{"atomic:operations":[{"op":"remove","href":"/blogs/1"}]}`,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/vnd.api+json",
+			expectedBody:        `{"errors":[{"title":"Bad Request","detail":"unsupported resource type \"blogs\"","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/0/href"}}]}`,
+		},
+		{
+			name:                "missing extension",
+			contentType:         "application/vnd.api+json",
+			body:                `{"atomic:operations":[]}`,
+			expectedStatus:      http.StatusUnsupportedMediaType,
+			expectedContentType: "application/vnd.api+json",
+			expectedBody:        `{"errors":[{"title":"Unsupported Media Type","detail":"atomic operations must be sent with the media type application/vnd.api+json;ext=\"https://jsonapi.org/ext/atomic\"","status":"415"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			committed := false
+			r := httptest.NewRequest(http.MethodPost, "/operations", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", test.contentType)
+			w := httptest.NewRecorder()
+			newAtomicProcessor(&committed).ServeHTTP(w, r)
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, test.expectedBody, strings.TrimSpace(w.Body.String()))
+			if test.expectedContentType != "" {
+				assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, test.expectedCommitted, committed)
+		})
+	}
+}
+
+func TestDefaultDecoder_AtomicOperations(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/operations", bytes.NewBufferString(`{"atomic:operations":[{"op":"remove","ref":{"type":"posts","id":"1"}}]}`))
+	r.Header.Set("Content-Type", atomicMediaType)
+	var ops []*render.Operation
+	if assert.NoError(t, render.DefaultDecoder(r, &ops)) && assert.Len(t, ops, 1) {
+		assert.Equal(t, render.OpRemove, ops[0].Op)
+		assert.Equal(t, &render.OperationRef{Type: "posts", ID: "1"}, ops[0].Ref)
+	}
+
+	t.Run("should resolve href", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/operations", bytes.NewBufferString(`{"atomic:operations":[{"op":"remove","href":"/blogs/1"},{"op":"add","href":"/blogs/1/relationships/posts","data":[]}]}`))
+		r.Header.Set("Content-Type", atomicMediaType)
+		var ops []*render.Operation
+		if assert.NoError(t, render.DefaultDecoder(r, &ops)) && assert.Len(t, ops, 2) {
+			assert.Equal(t, &render.OperationRef{Type: "blogs", ID: "1"}, ops[0].Ref)
+			assert.Equal(t, "blogs", ops[0].Type())
+			assert.Equal(t, &render.OperationRef{Type: "blogs", ID: "1", Relationship: "posts"}, ops[1].Ref)
+		}
+	})
+}
+
+func TestAtomicProcessor_ClientIDs(t *testing.T) {
+	body := `{"atomic:operations":[{"op":"add","data":{"type":"posts","id":"5","attributes":{"title":"first"}}}]}`
+
+	t.Run("should apply the client-generated id policy to add operations", func(t *testing.T) {
+		committed := false
+		r := httptest.NewRequest(http.MethodPost, "/operations", strings.NewReader(body))
+		r.Header.Set("Content-Type", atomicMediaType)
+		render.ClientIDs(r, render.ClientIDPolicy{Mode: render.ClientIDForbidden})
+		w := httptest.NewRecorder()
+		newAtomicProcessor(&committed).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Forbidden","detail":"client-generated ids are not supported","status":"403","code":"client_id_forbidden","source":{"pointer":"/atomic:operations/0/data/id"}}]}`, strings.TrimSpace(w.Body.String()))
+		assert.False(t, committed)
+	})
+
+	t.Run("should not apply the client-generated id policy to update operations", func(t *testing.T) {
+		committed := false
+		r := httptest.NewRequest(http.MethodPost, "/operations", strings.NewReader(`{"atomic:operations":[{"op":"add","data":{"type":"posts","attributes":{"title":"first"}}},{"op":"update","ref":{"type":"posts","id":"1"},"data":{"type":"posts","id":"1","attributes":{"title":"renamed"}}}]}`))
+		r.Header.Set("Content-Type", atomicMediaType)
+		render.ClientIDs(r, render.ClientIDPolicy{Mode: render.ClientIDForbidden})
+		w := httptest.NewRecorder()
+		newAtomicProcessor(&committed).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, committed)
+	})
+}
+
+func TestAtomicProcessor_BasePath(t *testing.T) {
+	serve := func(body string) *httptest.ResponseRecorder {
+		committed := false
+		p := newAtomicProcessor(&committed)
+		p.BasePath = "/api/v1/"
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/operations", strings.NewReader(body))
+		r.Header.Set("Content-Type", atomicMediaType)
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should strip the base path from href", func(t *testing.T) {
+		w := serve(`{"atomic:operations":[{"op":"add","data":{"type":"posts","attributes":{"title":"first"}}},{"op":"update","href":"https://example.com/api/v1/posts/1","data":{"type":"posts","id":"1","attributes":{"title":"renamed"}}},{"op":"remove","href":"/api/v1/posts/1"}]}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"atomic:results":[{"data":{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":"first"},"relationships":{"comments":{"data":[]}}}},{"data":{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":"renamed"},"relationships":{"comments":{"data":[]}}}},{}]}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should report href outside of the base path", func(t *testing.T) {
+		w := serve(`{"atomic:operations":[{"op":"remove","href":"/posts/1"},{"op":"remove","href":"/api/v10/posts/1"}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"invalid `+"`href`"+` \"/posts/1\", expected /api/v1/TYPE, /api/v1/TYPE/ID or /api/v1/TYPE/ID/relationships/NAME","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/0/href"}},{"title":"Bad Request","detail":"invalid `+"`href`"+` \"/api/v10/posts/1\", expected /api/v1/TYPE, /api/v1/TYPE/ID or /api/v1/TYPE/ID/relationships/NAME","status":"400","code":"invalid_operation","source":{"pointer":"/atomic:operations/1/href"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+}
+
+func TestAtomicResults(t *testing.T) {
+
+	t.Run("should respond result data not given as a pointer with 500 Internal Server Error", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/operations", nil)
+		r.Header.Set("Content-Type", atomicMediaType)
+		w := httptest.NewRecorder()
+		assert.NotPanics(t, func() {
+			render.JSONAPI(w, r, render.AtomicResults{{Data: Post{ID: 1}}})
+		})
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"500"`)
+	})
+}
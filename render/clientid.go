@@ -0,0 +1,82 @@
+package render
+
+import (
+	"context"
+	"net/http"
+)
+
+// ClientIDsCtxKey is the context key holding the ClientIDPolicy set with ClientIDs
+var ClientIDsCtxKey = &contextKey{"ClientIDs"}
+
+// ClientIDMode tells whether resources may be created with a client-generated id,
+// see https://jsonapi.org/format/#crud-creating-client-ids
+type ClientIDMode int
+
+const (
+	// ClientIDAllowed accepts resources with or without a client-generated id
+	ClientIDAllowed ClientIDMode = iota
+	// ClientIDForbidden responds resources with a client-generated id with 403 Forbidden
+	ClientIDForbidden
+	// ClientIDRequired responds resources without a client-generated id with 400 Bad Request
+	ClientIDRequired
+)
+
+// ClientIDPolicy is the client-generated id policy applied by DefaultDecoder to POST requests
+// and by AtomicProcessor to `add` operations
+type ClientIDPolicy struct {
+	Mode ClientIDMode
+	// Validate, if set, checks the client-generated ids that are allowed (e.g. UUID format).
+	// Errors are responded with 400 Bad Request unless they are ErrorObjecter (e.g. 409 Conflict for existing ids)
+	Validate func(id string) error
+}
+
+// ClientIDs sets the client-generated id policy DefaultDecoder applies to the primary data of POST requests
+// and AtomicProcessor to the resources of `add` operations.
+// Without a policy client-generated ids are allowed and decoded into the `primary` field
+func ClientIDs(r *http.Request, policy ClientIDPolicy) {
+	*r = *r.WithContext(context.WithValue(r.Context(), ClientIDsCtxKey, policy))
+}
+
+func getClientIDPolicy(ctx context.Context) *ClientIDPolicy {
+	policy, ok := ctx.Value(ClientIDsCtxKey).(ClientIDPolicy)
+	if !ok {
+		return nil
+	}
+	return &policy
+}
+
+// check checks `id`, the id of the primary data of the request document, against the policy
+func (p *ClientIDPolicy) check(id string) error {
+	switch {
+	case id == "" && p.Mode == ClientIDRequired:
+		return newClientIDError(http.StatusBadRequest, "missing_client_id", "a client-generated id is required")
+	case id == "":
+		return nil
+	case p.Mode == ClientIDForbidden:
+		return newClientIDError(http.StatusForbidden, "client_id_forbidden", "client-generated ids are not supported")
+	case p.Validate == nil:
+		return nil
+	}
+
+	err := p.Validate(id)
+	if err == nil {
+		return nil
+	}
+	if obj, ok := asErrorObject(err); ok {
+		withSource := *obj
+		if withSource.Source == nil {
+			withSource.Source = &ErrorSource{Pointer: "/data/id"}
+		}
+		return &withSource
+	}
+	return newClientIDError(http.StatusBadRequest, "invalid_client_id", err.Error())
+}
+
+func newClientIDError(status int, code, detail string) error {
+	return &Error{
+		Status: status,
+		Code:   code,
+		Detail: detail,
+		Source: &ErrorSource{Pointer: "/data/id"},
+	}
+}
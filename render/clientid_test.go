@@ -0,0 +1,70 @@
+package render_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIDs(t *testing.T) {
+	withID := `{"data":{"type":"blogs","id":"11","attributes":{"title":"The Best Blog"}}}`
+	withoutID := `{"data":{"type":"blogs","attributes":{"title":"The Best Blog"}}}`
+	validate := func(id string) error {
+		switch id {
+		case "11":
+			return nil
+		case "12":
+			return &render.Error{Status: http.StatusConflict, Detail: "blog 12 already exists"}
+		}
+		return errors.New("ids must be 11")
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		policy     *render.ClientIDPolicy
+		body       string
+		expectedID int
+		status     int
+		code       string
+	}{
+		{name: "no policy", method: http.MethodPost, body: withID, expectedID: 11},
+		{name: "allowed", method: http.MethodPost, policy: &render.ClientIDPolicy{}, body: withID, expectedID: 11},
+		{name: "allowed without id", method: http.MethodPost, policy: &render.ClientIDPolicy{}, body: withoutID},
+		{name: "forbidden", method: http.MethodPost, policy: &render.ClientIDPolicy{Mode: render.ClientIDForbidden}, body: withID, status: http.StatusForbidden, code: "client_id_forbidden"},
+		{name: "forbidden without id", method: http.MethodPost, policy: &render.ClientIDPolicy{Mode: render.ClientIDForbidden}, body: withoutID},
+		{name: "forbidden on update", method: http.MethodPatch, policy: &render.ClientIDPolicy{Mode: render.ClientIDForbidden}, body: withID, expectedID: 11},
+		{name: "required", method: http.MethodPost, policy: &render.ClientIDPolicy{Mode: render.ClientIDRequired}, body: withID, expectedID: 11},
+		{name: "required without id", method: http.MethodPost, policy: &render.ClientIDPolicy{Mode: render.ClientIDRequired}, body: withoutID, status: http.StatusBadRequest, code: "missing_client_id"},
+		{name: "valid", method: http.MethodPost, policy: &render.ClientIDPolicy{Validate: validate}, body: withID, expectedID: 11},
+		{name: "invalid", method: http.MethodPost, policy: &render.ClientIDPolicy{Validate: validate}, body: `{"data":{"type":"blogs","id":"13"}}`, status: http.StatusBadRequest, code: "invalid_client_id"},
+		{name: "validator error object", method: http.MethodPost, policy: &render.ClientIDPolicy{Validate: validate}, body: `{"data":{"type":"blogs","id":"12"}}`, status: http.StatusConflict},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/blogs", bytes.NewBufferString(test.body))
+			r.Header.Set("Content-Type", "application/vnd.api+json")
+			if test.policy != nil {
+				render.ClientIDs(r, *test.policy)
+			}
+			var v Blog
+			err := render.DefaultDecoder(r, &v)
+			if test.status == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedID, v.ID)
+				return
+			}
+			var obj *render.Error
+			if assert.True(t, errors.As(err, &obj)) {
+				assert.Equal(t, test.status, obj.Status)
+				assert.Equal(t, test.code, obj.Code)
+				assert.Equal(t, &render.ErrorSource{Pointer: "/data/id"}, obj.Source)
+			}
+			assert.Zero(t, v.ID)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// IDParamCtxKey is the context key holding the URL parameter set with IDParam
+var IDParamCtxKey = &contextKey{"IDParam"}
+
+// IDParam sets the chi URL parameter (e.g. `id` for `/blogs/{id}`) DefaultDecoder matches the `id`
+// of the primary data against, a different id is a ConflictError and a missing id in PATCH requests is a DecodeError
+func IDParam(r *http.Request, param string) {
+	*r = *r.WithContext(context.WithValue(r.Context(), IDParamCtxKey, param))
+}
+
+func getIDParam(ctx context.Context) string {
+	param, _ := ctx.Value(IDParamCtxKey).(string)
+	return param
+}
+
+// ConflictError is returned when decoding primary data whose `type` or `id` does not match the endpoint,
+// it renders as a 409 Conflict error pointing to the member, see https://jsonapi.org/format/#crud-creating-responses-409
+type ConflictError struct {
+	// Member is the conflicting member of the primary data, `type` or `id`
+	Member string
+	// Expected is the value of the endpoint
+	Expected string
+	// Actual is the value of the request document
+	Actual string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("expected resource %s %q, got %q", e.Member, e.Expected, e.Actual)
+}
+
+// JSONAPIError implements ErrorObjecter
+func (e *ConflictError) JSONAPIError() *Error {
+	return &Error{
+		Status: http.StatusConflict,
+		Code:   e.Member + "_mismatch",
+		Detail: e.Error(),
+		Source: &ErrorSource{Pointer: "/data/" + e.Member},
+	}
+}
+
+// StatusCode implements StatusCoder
+func (e *ConflictError) StatusCode() int {
+	return http.StatusConflict
+}
@@ -0,0 +1,104 @@
+package render_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONAPITypeMismatch(t *testing.T) {
+
+	t.Run("should return a ConflictError pointing to the type", func(t *testing.T) {
+		var v Blog
+		err := render.DecodeJSONAPI(bytes.NewBufferString(`{"data":{"type":"posts","attributes":{"title":"The Best Post"}}}`), &v)
+		var conflict *render.ConflictError
+		if assert.True(t, errors.As(err, &conflict)) {
+			assert.Equal(t, &render.ConflictError{Member: "type", Expected: "blogs", Actual: "posts"}, conflict)
+			assert.Equal(t, &render.Error{
+				Status: http.StatusConflict,
+				Code:   "type_mismatch",
+				Detail: `expected resource type "blogs", got "posts"`,
+				Source: &render.ErrorSource{Pointer: "/data/type"},
+			}, conflict.JSONAPIError())
+		}
+		assert.Empty(t, v.Title)
+	})
+}
+
+func TestIDParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    string
+		body     string
+		status   int
+		respBody string
+	}{
+		{name: "matching id", param: "id", body: `{"data":{"type":"blogs","id":"1","attributes":{"title":"New"}}}`, status: http.StatusOK},
+		{name: "without IDParam", body: `{"data":{"type":"blogs","id":"2","attributes":{"title":"New"}}}`, status: http.StatusOK},
+		{name: "without id nor IDParam", body: `{"data":{"type":"blogs","attributes":{"title":"New"}}}`, status: http.StatusOK},
+		{
+			name:     "without id",
+			param:    "id",
+			body:     `{"data":{"type":"blogs","attributes":{"title":"New"}}}`,
+			status:   http.StatusBadRequest,
+			respBody: `{"errors":[{"title":"Bad Request","detail":"missing resource id","status":"400","code":"missing_id","source":{"pointer":"/data/id"}}]}`,
+		},
+		{
+			name:     "without type",
+			param:    "id",
+			body:     `{"data":{"id":"1","attributes":{"title":"New"}}}`,
+			status:   http.StatusBadRequest,
+			respBody: `{"errors":[{"title":"Bad Request","detail":"missing resource type","status":"400","code":"missing_type","source":{"pointer":"/data/type"}}]}`,
+		},
+		{
+			name:     "mismatching id",
+			param:    "id",
+			body:     `{"data":{"type":"blogs","id":"2","attributes":{"title":"New"}}}`,
+			status:   http.StatusConflict,
+			respBody: `{"errors":[{"title":"Conflict","detail":"expected resource id \"1\", got \"2\"","status":"409","code":"id_mismatch","source":{"pointer":"/data/id"}}]}`,
+		},
+		{
+			name:     "mismatching type",
+			param:    "id",
+			body:     `{"data":{"type":"posts","id":"1","attributes":{"title":"New"}}}`,
+			status:   http.StatusConflict,
+			respBody: `{"errors":[{"title":"Conflict","detail":"expected resource type \"blogs\", got \"posts\"","status":"409","code":"type_mismatch","source":{"pointer":"/data/type"}}]}`,
+		},
+		{
+			name:     "mismatching type with a numeric id",
+			param:    "id",
+			body:     `{"data":{"type":"posts","id":1}}`,
+			status:   http.StatusConflict,
+			respBody: `{"errors":[{"title":"Conflict","detail":"expected resource type \"blogs\", got \"posts\"","status":"409","code":"type_mismatch","source":{"pointer":"/data/type"}}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := chi.NewRouter()
+			router.Patch("/blogs/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if test.param != "" {
+					render.IDParam(r, test.param)
+				}
+				var v Blog
+				if err := render.DefaultDecoder(r, &v); err != nil {
+					render.JSONAPI(w, r, err)
+				}
+			})
+
+			r := httptest.NewRequest(http.MethodPatch, "/blogs/1", bytes.NewBufferString(test.body))
+			r.Header.Set("Content-Type", "application/vnd.api+json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			assert.Equal(t, test.status, w.Code)
+			if test.respBody != "" {
+				assert.Equal(t, test.respBody, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
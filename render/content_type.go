@@ -1,7 +1,9 @@
 package render
 
 import (
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 )
 import chi_render "github.com/go-chi/render"
@@ -10,6 +12,18 @@ const (
 	ContentTypeJSONAPI chi_render.ContentType = iota + 1000
 )
 
+// PreferredMediaTypes is the server preferred order of media types, GetAcceptedContentType
+// uses it to choose among media types the client finds equally acceptable
+var PreferredMediaTypes = []string{
+	"application/vnd.api+json",
+	"application/json",
+	"application/xml",
+	"text/xml",
+	"text/html",
+	"text/plain",
+	"text/event-stream",
+}
+
 // GetContentType extends go-ci/render to support application/vnd.api+json
 func GetContentType(s string) chi_render.ContentType {
 	s = strings.TrimSpace(strings.Split(s, ";")[0])
@@ -21,18 +35,14 @@ func GetContentType(s string) chi_render.ContentType {
 	}
 }
 
+// GetAcceptedContentType returns the ContentType to respond with based on context or the request Accept header,
+// negotiated as per RFC 7231 (quality values, wildcards and specificity) against PreferredMediaTypes
 func GetAcceptedContentType(r *http.Request) chi_render.ContentType {
 	if contentType, ok := r.Context().Value(chi_render.ContentTypeCtxKey).(chi_render.ContentType); ok {
 		return contentType
 	}
 
-	var contentType chi_render.ContentType
-
-	// Parse request Accept header.
-	fields := strings.Split(r.Header.Get("Accept"), ",")
-	if len(fields) > 0 {
-		contentType = GetContentType(strings.TrimSpace(fields[0]))
-	}
+	contentType := GetContentType(negotiate(parseAccept(r.Header.Get("Accept")), PreferredMediaTypes))
 
 	if contentType == chi_render.ContentTypeUnknown {
 		contentType = chi_render.ContentTypePlainText
@@ -41,6 +51,76 @@ func GetAcceptedContentType(r *http.Request) chi_render.ContentType {
 	return contentType
 }
 
+// mediaRange is an entry of an Accept header
+type mediaRange struct {
+	mediaType string
+	params    map[string]string
+	q         float64
+}
+
+// specificity ranks exact media types over `type/*` over `*/*`, and media types with parameters over the ones without
+func (m mediaRange) specificity() int {
+	specificity := len(m.params)
+	if m.mediaType == "*/*" {
+		return specificity
+	}
+	if strings.HasSuffix(m.mediaType, "/*") {
+		return specificity + 100
+	}
+	return specificity + 200
+}
+
+// matches reports whether `mediaType` (without parameters) falls within the range
+func (m mediaRange) matches(mediaType string) bool {
+	if m.mediaType == "*/*" || m.mediaType == mediaType {
+		return true
+	}
+	return strings.HasSuffix(m.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*"))
+}
+
+// parseAccept parses an Accept header, skipping invalid entries
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, field := range strings.Split(header, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(field)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+			delete(params, "q")
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, params: params, q: q})
+	}
+	return ranges
+}
+
+// negotiate returns the offer with the highest quality, ties are broken by the specificity of the
+// matching range and then by the order of `offers`, returns "" if no offer is acceptable
+func negotiate(ranges []mediaRange, offers []string) string {
+	var best string
+	bestQ, bestSpecificity := 0.0, -1
+	for _, offer := range offers {
+		// the quality of an offer is given by its most specific matching range
+		q, specificity := 0.0, -1
+		for _, m := range ranges {
+			if m.matches(offer) && m.specificity() > specificity {
+				q, specificity = m.q, m.specificity()
+			}
+		}
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best
+}
+
 // GetRequestContentType is a helper function that returns ContentType based on
 // context or request headers.
 func GetRequestContentType(r *http.Request) chi_render.ContentType {
@@ -85,7 +85,7 @@ type mockHandler struct {
 }
 
 func (h mockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", string(r.Context().Value(chi_render.ContentTypeCtxKey).(chi_render.ContentType)))
+	w.Header().Set("Content-Type", fmt.Sprint(r.Context().Value(chi_render.ContentTypeCtxKey).(chi_render.ContentType)))
 }
 
 // TestSetContentType ensures that go-chi/render SetContentType middleware works with JSON API content type
@@ -111,7 +111,7 @@ func TestSetContentType(t *testing.T) {
 			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
 			w := httptest.NewRecorder()
 			mw(nextHandler).ServeHTTP(w, r)
-			assert.Equal(t, string(test.contentType), w.Header().Get("Content-Type"))
+			assert.Equal(t, fmt.Sprint(test.contentType), w.Header().Get("Content-Type"))
 		})
 	}
 }
@@ -132,6 +132,51 @@ func TestGetAcceptedContentType(t *testing.T) {
 			acceptHeader:        "application/json",
 			expectedContentType: chi_render.ContentTypeJSON,
 		},
+		{
+			name:                "highest quality value wins",
+			acceptHeader:        "text/html;q=0.1, application/vnd.api+json",
+			expectedContentType: render.ContentTypeJSONAPI,
+		},
+		{
+			name:                "any media type uses server preferred order",
+			acceptHeader:        "*/*",
+			expectedContentType: render.ContentTypeJSONAPI,
+		},
+		{
+			name:                "subtype wildcard uses server preferred order",
+			acceptHeader:        "text/*",
+			expectedContentType: chi_render.ContentTypeXML,
+		},
+		{
+			name:                "more specific range takes precedence over wildcard",
+			acceptHeader:        "*/*;q=0.8, application/json",
+			expectedContentType: chi_render.ContentTypeJSON,
+		},
+		{
+			name:                "zero quality value excludes a media type",
+			acceptHeader:        "application/vnd.api+json;q=0, */*",
+			expectedContentType: chi_render.ContentTypeJSON,
+		},
+		{
+			name:                "browser accept header",
+			acceptHeader:        "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			expectedContentType: chi_render.ContentTypeHTML,
+		},
+		{
+			name:                "invalid quality values are ignored",
+			acceptHeader:        "application/vnd.api+json;q=2, application/json",
+			expectedContentType: chi_render.ContentTypeJSON,
+		},
+		{
+			name:                "defaults to plain text when nothing is acceptable",
+			acceptHeader:        "image/png",
+			expectedContentType: chi_render.ContentTypePlainText,
+		},
+		{
+			name:                "defaults to plain text without Accept header",
+			acceptHeader:        "",
+			expectedContentType: chi_render.ContentTypePlainText,
+		},
 	}
 
 	for _, test := range tests {
@@ -142,3 +187,12 @@ func TestGetAcceptedContentType(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAcceptedContentType_PreferredMediaTypes(t *testing.T) {
+	defer func(preferred []string) { render.PreferredMediaTypes = preferred }(render.PreferredMediaTypes)
+	render.PreferredMediaTypes = []string{"application/json", "application/vnd.api+json"}
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	r.Header.Set("Accept", "application/*")
+	assert.Equal(t, chi_render.ContentType(chi_render.ContentTypeJSON), render.GetAcceptedContentType(r))
+}
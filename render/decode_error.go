@@ -0,0 +1,280 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/google/jsonapi"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// iso8601Layout is the layout google/jsonapi parses `iso8601` attributes with
+const iso8601Layout = "2006-01-02T15:04:05Z"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DecodeError is returned when a request document cannot be decoded into a resource,
+// it renders as a 400 Bad Request error pointing to the offending member.
+// Codes are `malformed_document` (invalid JSON), `invalid_data` (primary data, attributes or relationships
+// that are not objects), `missing_type`, `invalid_id`, `missing_id` (see IDParam), `invalid_attribute` and `invalid_relationship`, and `unknown_member`,
+// `unknown_attribute` and `unknown_relationship` when decoding strictly (see Strict)
+type DecodeError struct {
+	// Pointer is a JSON Pointer to the offending member, e.g. `/data/attributes/view_count`
+	Pointer string
+	// Code is the application-specific error code
+	Code string
+	// Expected describes the expected JSON value, e.g. `integer`
+	Expected string
+	// Err is the underlying error, if any
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	switch {
+	case e.Expected != "" && e.Pointer != "":
+		return fmt.Sprintf("invalid value of %s, expected %s", e.Pointer, e.Expected)
+	case e.Err != nil:
+		return e.Err.Error()
+	}
+	return "request body is not a valid JSON:API document"
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// JSONAPIError implements ErrorObjecter, the expected value is rendered in the `expected` meta member
+func (e *DecodeError) JSONAPIError() *Error {
+	obj := &Error{Status: http.StatusBadRequest, Code: e.Code, Detail: e.Error()}
+	if e.Pointer != "" {
+		obj.Source = &ErrorSource{Pointer: e.Pointer}
+	}
+	if e.Expected != "" {
+		obj.Meta = map[string]interface{}{"expected": e.Expected}
+	}
+	return obj
+}
+
+// checkDocument checks that the primary data of the document `body` can be decoded into `v`, a struct pointer,
+// returning DecodeError for each invalid member so that google/jsonapi does not fail on them,
+// and for each unknown member when `strict`
+func checkDocument(body []byte, v interface{}, strict bool) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return &DecodeError{Code: "malformed_document", Err: err}
+	}
+	res := resource.Of(reflect.TypeOf(v))
+	if res == nil || reflect.TypeOf(v).Kind() != reflect.Ptr {
+		return nil
+	}
+	data, ok := doc["data"].(map[string]interface{})
+	if !ok {
+		return &DecodeError{Pointer: "/data", Code: "invalid_data", Expected: "resource object"}
+	}
+
+	var errs Errors
+	if strict {
+		errs = checkUnknownMembers(doc, res)
+	}
+	if err := checkID(data["id"], "/data/id", res); err != nil {
+		errs = append(errs, err)
+	}
+	if attributes, ok := data["attributes"]; ok {
+		attributes, ok := attributes.(map[string]interface{})
+		if !ok {
+			return append(errs, &DecodeError{Pointer: "/data/attributes", Code: "invalid_data", Expected: "object"})
+		}
+		for _, attr := range res.Attributes {
+			if expected, ok := checkAttribute(attributes[attr.Name], attr); !ok {
+				errs = append(errs, &DecodeError{Pointer: "/data/attributes/" + attr.Name, Code: "invalid_attribute", Expected: expected})
+			}
+		}
+	}
+	if relationships, ok := data["relationships"]; ok {
+		relationships, ok := relationships.(map[string]interface{})
+		if !ok {
+			return append(errs, &DecodeError{Pointer: "/data/relationships", Code: "invalid_data", Expected: "object"})
+		}
+		for _, rel := range res.Relationships {
+			if value, ok := relationships[rel.Name]; ok {
+				errs = append(errs, checkRelationship(value, "/data/relationships/"+rel.Name, rel)...)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkID checks that `id`, if any, is a string that can be set to the primary field of `res`
+func checkID(id interface{}, pointer string, res *resource.Resource) error {
+	if id == nil {
+		return nil
+	}
+	s, ok := id.(string)
+	if !ok {
+		return &DecodeError{Pointer: pointer, Code: "invalid_id", Expected: "string"}
+	}
+	primary := reflect.New(res.Primary.Type).Elem()
+	switch primary.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if err := setPrimary(primary, s); err != nil {
+			return &DecodeError{Pointer: pointer, Code: "invalid_id", Expected: "integer string", Err: err}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if err := setPrimary(primary, s); err != nil {
+			return &DecodeError{Pointer: pointer, Code: "invalid_id", Expected: "positive integer string", Err: err}
+		}
+	}
+	return nil
+}
+
+// checkAttribute reports whether `value` can be decoded into the attribute `attr`, along with the expected JSON value
+func checkAttribute(value interface{}, attr *resource.Field) (string, bool) {
+	if value == nil {
+		return "", true
+	}
+	t := attr.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		if attr.HasOption("iso8601") {
+			s, ok := value.(string)
+			if !ok {
+				return "ISO 8601 timestamp", false
+			}
+			_, err := time.Parse(iso8601Layout, s)
+			return "ISO 8601 timestamp", err == nil
+		}
+		_, ok := value.(float64)
+		return "unix timestamp", ok
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		_, ok := value.(string)
+		return "string", ok
+	case reflect.Bool:
+		_, ok := value.(bool)
+		return "boolean", ok
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) {
+			return "integer", false
+		}
+		min := int64(-1) << uint(t.Bits()-1)
+		expected := fmt.Sprintf("integer between %d and %d", min, -(min + 1))
+		return expected, f >= math.MinInt64 && f < math.MaxInt64 && !reflect.New(t).Elem().OverflowInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) {
+			return "integer", false
+		}
+		expected := fmt.Sprintf("integer between 0 and %d", uint64(1)<<uint(t.Bits())-1)
+		return expected, f >= 0 && f < math.MaxUint64 && !reflect.New(t).Elem().OverflowUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		_, ok := value.(float64)
+		return "number", ok
+	case reflect.Struct, reflect.Map:
+		_, ok := value.(map[string]interface{})
+		return "object", ok
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return "array", false
+		}
+		switch t.Elem().Kind() {
+		case reflect.String:
+			for _, item := range items {
+				if _, ok := item.(string); !ok {
+					return "array of strings", false
+				}
+			}
+		case reflect.Struct:
+			for _, item := range items {
+				if _, ok := item.(map[string]interface{}); !ok {
+					return "array of objects", false
+				}
+			}
+		}
+		return "array", true
+	}
+	return "", true
+}
+
+// checkRelationship checks that `value`, at `pointer`, is a relationship object whose linkage can be decoded
+// into the relationship `rel`
+func checkRelationship(value interface{}, pointer string, rel *resource.Field) (errs Errors) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return Errors{&DecodeError{Pointer: pointer, Code: "invalid_relationship", Expected: "relationship object"}}
+	}
+	data, ok := object["data"]
+	if !ok {
+		return nil
+	}
+
+	var identifiers []interface{}
+	var pointers []string
+	if rel.Many {
+		items, ok := data.([]interface{})
+		if !ok {
+			return Errors{&DecodeError{Pointer: pointer + "/data", Code: "invalid_relationship", Expected: "array of resource identifiers"}}
+		}
+		for i, item := range items {
+			identifiers, pointers = append(identifiers, item), append(pointers, pointer+"/data/"+strconv.Itoa(i))
+		}
+	} else if data != nil {
+		identifiers, pointers = append(identifiers, data), append(pointers, pointer+"/data")
+	}
+
+	related := rel.Related()
+	for i, identifier := range identifiers {
+		identifier, ok := identifier.(map[string]interface{})
+		if !ok {
+			errs = append(errs, &DecodeError{Pointer: pointers[i], Code: "invalid_relationship", Expected: "resource identifier"})
+			continue
+		}
+		if identifier["id"] == nil && identifier["lid"] == nil {
+			errs = append(errs, &DecodeError{Pointer: pointers[i], Code: "invalid_relationship", Err: errors.New("missing resource identifier `id` or `lid`")})
+			continue
+		}
+		if related == nil || identifier["id"] == nil {
+			continue
+		}
+		switch typ, _ := identifier["type"].(string); {
+		case typ == "":
+			errs = append(errs, &DecodeError{Pointer: pointers[i] + "/type", Code: "invalid_relationship", Expected: "resource type"})
+			continue
+		case typ != related.Name:
+			errs = append(errs, newRelationshipError(http.StatusConflict, pointers[i]+"/type", fmt.Sprintf("expected resource type %q, got %q", related.Name, typ)))
+			continue
+		}
+		if err := checkID(identifier["id"], pointers[i]+"/id", related); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// decodeError wraps the errors google/jsonapi returns on invalid attribute values as DecodeError
+func decodeError(err error) error {
+	var ptrErr jsonapi.ErrUnsupportedPtrType
+	switch {
+	case errors.Is(err, jsonapi.ErrInvalidTime), errors.Is(err, jsonapi.ErrInvalidISO8601),
+		errors.Is(err, jsonapi.ErrUnknownFieldNumberType), errors.Is(err, jsonapi.ErrInvalidType),
+		errors.As(err, &ptrErr):
+		return &DecodeError{Pointer: "/data/attributes", Code: "invalid_attribute", Err: err}
+	case errors.Is(err, jsonapi.ErrBadJSONAPIID):
+		return &DecodeError{Pointer: "/data/id", Code: "invalid_id", Err: err}
+	}
+	return err
+}
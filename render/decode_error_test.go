@@ -0,0 +1,158 @@
+package render_test
+
+import (
+	"bytes"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Event struct {
+	ID       string     `jsonapi:"primary,events"`
+	Name     string     `jsonapi:"attr,name"`
+	Public   bool       `jsonapi:"attr,public"`
+	Score    float64    `jsonapi:"attr,score"`
+	Tags     []string   `jsonapi:"attr,tags"`
+	StartsAt time.Time  `jsonapi:"attr,starts_at,iso8601"`
+	EndsAt   *time.Time `jsonapi:"attr,ends_at"`
+	Capacity *int       `jsonapi:"attr,capacity"`
+	Seats    int8       `jsonapi:"attr,seats"`
+	Visitors uint       `jsonapi:"attr,visitors"`
+}
+
+func TestDecodeJSONAPIErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        interface{}
+		body     string
+		expected []*render.Error
+	}{
+		{
+			name:     "malformed document",
+			v:        &Blog{},
+			body:     `{"data":{{{`,
+			expected: []*render.Error{{Status: http.StatusBadRequest, Code: "malformed_document", Detail: "invalid character '{' looking for beginning of object key string"}},
+		},
+		{
+			name:     "missing data",
+			v:        &Blog{},
+			body:     `{"meta":{}}`,
+			expected: []*render.Error{{Status: http.StatusBadRequest, Code: "invalid_data", Detail: "invalid value of /data, expected resource object", Source: &render.ErrorSource{Pointer: "/data"}, Meta: map[string]interface{}{"expected": "resource object"}}},
+		},
+		{
+			name:     "attributes not an object",
+			v:        &Blog{},
+			body:     `{"data":{"type":"blogs","attributes":[]}}`,
+			expected: []*render.Error{{Status: http.StatusBadRequest, Code: "invalid_data", Detail: "invalid value of /data/attributes, expected object", Source: &render.ErrorSource{Pointer: "/data/attributes"}, Meta: map[string]interface{}{"expected": "object"}}},
+		},
+		{
+			name: "invalid attributes",
+			v:    &Blog{},
+			body: `{"data":{"type":"blogs","attributes":{"title":1,"view_count":"many","current_post_id":1.5,"created_at":"yesterday"}}}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/title, expected string", Source: &render.ErrorSource{Pointer: "/data/attributes/title"}, Meta: map[string]interface{}{"expected": "string"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/current_post_id, expected integer", Source: &render.ErrorSource{Pointer: "/data/attributes/current_post_id"}, Meta: map[string]interface{}{"expected": "integer"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/created_at, expected unix timestamp", Source: &render.ErrorSource{Pointer: "/data/attributes/created_at"}, Meta: map[string]interface{}{"expected": "unix timestamp"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/view_count, expected integer", Source: &render.ErrorSource{Pointer: "/data/attributes/view_count"}, Meta: map[string]interface{}{"expected": "integer"}},
+			},
+		},
+		{
+			name: "invalid attributes of other types",
+			v:    &Event{},
+			body: `{"data":{"type":"events","attributes":{"name":null,"public":"yes","score":"high","tags":["a",1],"starts_at":"2020-01-01","ends_at":"tomorrow","capacity":true}}}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/public, expected boolean", Source: &render.ErrorSource{Pointer: "/data/attributes/public"}, Meta: map[string]interface{}{"expected": "boolean"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/score, expected number", Source: &render.ErrorSource{Pointer: "/data/attributes/score"}, Meta: map[string]interface{}{"expected": "number"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/tags, expected array of strings", Source: &render.ErrorSource{Pointer: "/data/attributes/tags"}, Meta: map[string]interface{}{"expected": "array of strings"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/starts_at, expected ISO 8601 timestamp", Source: &render.ErrorSource{Pointer: "/data/attributes/starts_at"}, Meta: map[string]interface{}{"expected": "ISO 8601 timestamp"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/ends_at, expected unix timestamp", Source: &render.ErrorSource{Pointer: "/data/attributes/ends_at"}, Meta: map[string]interface{}{"expected": "unix timestamp"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/capacity, expected integer", Source: &render.ErrorSource{Pointer: "/data/attributes/capacity"}, Meta: map[string]interface{}{"expected": "integer"}},
+			},
+		},
+		{
+			name: "integers out of range",
+			v:    &Event{},
+			body: `{"data":{"type":"events","attributes":{"capacity":1e30,"seats":300,"visitors":-1}}}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/capacity, expected integer between -9223372036854775808 and 9223372036854775807", Source: &render.ErrorSource{Pointer: "/data/attributes/capacity"}, Meta: map[string]interface{}{"expected": "integer between -9223372036854775808 and 9223372036854775807"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/seats, expected integer between -128 and 127", Source: &render.ErrorSource{Pointer: "/data/attributes/seats"}, Meta: map[string]interface{}{"expected": "integer between -128 and 127"}},
+				{Status: http.StatusBadRequest, Code: "invalid_attribute", Detail: "invalid value of /data/attributes/visitors, expected integer between 0 and 18446744073709551615", Source: &render.ErrorSource{Pointer: "/data/attributes/visitors"}, Meta: map[string]interface{}{"expected": "integer between 0 and 18446744073709551615"}},
+			},
+		},
+		{
+			name:     "invalid id",
+			v:        &Blog{},
+			body:     `{"data":{"type":"blogs","id":"first"}}`,
+			expected: []*render.Error{{Status: http.StatusBadRequest, Code: "invalid_id", Detail: "invalid value of /data/id, expected integer string", Source: &render.ErrorSource{Pointer: "/data/id"}, Meta: map[string]interface{}{"expected": "integer string"}}},
+		},
+		{
+			name:     "id not a string",
+			v:        &Blog{},
+			body:     `{"data":{"type":"blogs","id":1}}`,
+			expected: []*render.Error{{Status: http.StatusBadRequest, Code: "invalid_id", Detail: "invalid value of /data/id, expected string", Source: &render.ErrorSource{Pointer: "/data/id"}, Meta: map[string]interface{}{"expected": "string"}}},
+		},
+		{
+			name: "invalid relationships",
+			v:    &Blog{},
+			body: `{"data":{"type":"blogs","relationships":{"posts":{"data":{"type":"posts","id":"1"}},"current_post":{"data":{"type":"posts","id":"first"}}}}}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: "invalid value of /data/relationships/posts/data, expected array of resource identifiers", Source: &render.ErrorSource{Pointer: "/data/relationships/posts/data"}, Meta: map[string]interface{}{"expected": "array of resource identifiers"}},
+				{Status: http.StatusBadRequest, Code: "invalid_id", Detail: "invalid value of /data/relationships/current_post/data/id, expected integer string", Source: &render.ErrorSource{Pointer: "/data/relationships/current_post/data/id"}, Meta: map[string]interface{}{"expected": "integer string"}},
+			},
+		},
+		{
+			name: "invalid resource identifiers",
+			v:    &Blog{},
+			body: `{"data":{"type":"blogs","relationships":{"posts":{"data":[1,{"id":"2"},{"type":"comments","id":"3"},{"type":"posts"}]}}}}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: "invalid value of /data/relationships/posts/data/0, expected resource identifier", Source: &render.ErrorSource{Pointer: "/data/relationships/posts/data/0"}, Meta: map[string]interface{}{"expected": "resource identifier"}},
+				{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: "invalid value of /data/relationships/posts/data/1/type, expected resource type", Source: &render.ErrorSource{Pointer: "/data/relationships/posts/data/1/type"}, Meta: map[string]interface{}{"expected": "resource type"}},
+				{
+					Status: http.StatusConflict,
+					Code:   "invalid_relationship",
+					Detail: `expected resource type "posts", got "comments"`,
+					Source: &render.ErrorSource{Pointer: "/data/relationships/posts/data/2/type"},
+				},
+				{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: "missing resource identifier `id` or `lid`", Source: &render.ErrorSource{Pointer: "/data/relationships/posts/data/3"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := render.DecodeJSONAPI(bytes.NewBufferString(test.body), test.v)
+			if assert.Error(t, err) {
+				assert.Equal(t, test.expected, errorObjects(t, err))
+			}
+		})
+	}
+}
+
+func TestDecodeJSONAPIValidAttributes(t *testing.T) {
+
+	t.Run("should decode attributes of every supported type", func(t *testing.T) {
+		body := `{"data":{"type":"events","id":"e1","attributes":{"name":"Launch","public":true,"score":4.5,"tags":["a","b"],"starts_at":"2020-01-01T10:00:00Z","ends_at":1577876400,"capacity":null}}}`
+		var v Event
+		assert.NoError(t, render.DecodeJSONAPI(bytes.NewBufferString(body), &v))
+		assert.Equal(t, "Launch", v.Name)
+		assert.Equal(t, []string{"a", "b"}, v.Tags)
+		assert.Equal(t, time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC), v.StartsAt)
+		assert.Nil(t, v.Capacity)
+	})
+}
+
+func TestDecodeErrorResponse(t *testing.T) {
+
+	t.Run("should render decode errors as 400 Bad Request", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/blogs", bytes.NewBufferString(`{"data":{"type":"blogs","attributes":{"view_count":"many"}}}`))
+		r.Header.Set("Content-Type", "application/vnd.api+json")
+		w := httptest.NewRecorder()
+		var v Blog
+		render.JSONAPI(w, r, render.DefaultDecoder(r, &v))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"invalid value of /data/attributes/view_count, expected integer","status":"400","code":"invalid_attribute","source":{"pointer":"/data/attributes/view_count"},"meta":{"expected":"integer"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+}
@@ -1,21 +1,41 @@
 package render
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/go-chi/chi"
 	chi_render "github.com/go-chi/render"
 	"github.com/google/jsonapi"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 )
 
-// DefaultDecoder decodes JSON API
+// DefaultDecoder decodes JSON API, the attributes and relationships present in the request are stored
+// in the request context (see GetPresentFields), the id of POST requests is checked against ClientIDs
+// and the one of other requests against the URL parameter set with IDParam, which PATCH requests must have.
+// Requests of routes using the Strict middleware are decoded with DecodeJSONAPIStrict
+// and decoded resources are validated (see Validate)
 // delegates any other content type to github.con/go-chi/render
 func DefaultDecoder(r *http.Request, v interface{}) error {
 	var err error
 
 	switch GetRequestContentType(r) {
 	case ContentTypeJSONAPI:
-		err = DecodeJSONAPI(r.Body, v)
+		if _, ok := v.(*[]*Operation); ok {
+			err = DecodeJSONAPI(r.Body, v)
+			break
+		}
+		var present *PresentFields
+		if present, err = decodeRequest(r, v); err != nil {
+			break
+		}
+		*r = *r.WithContext(context.WithValue(r.Context(), PresentFieldsCtxKey, present))
+		err = validate(v, present, r.Method == http.MethodPatch)
 	default:
 		err = chi_render.DefaultDecoder(r, v)
 	}
@@ -23,7 +43,123 @@ func DefaultDecoder(r *http.Request, v interface{}) error {
 	return err
 }
 
+// DecodeJSONAPI decodes a JSON:API document into `v`, a struct pointer,
+// or an Atomic Operations document when `v` is a *[]*Operation (see DecodeOperations).
+// Local ids (`lid`) of relationships referring to resources of the document with an `id` are resolved,
+// the local id of the resource is set when `v` is LocalIdentifiable.
+// A `type` other than the one of the `primary` tag of `v` is a ConflictError, invalid members are DecodeError
 func DecodeJSONAPI(r io.Reader, v interface{}) error {
 	defer io.Copy(ioutil.Discard, r)
-	return jsonapi.UnmarshalPayload(r, v)
+	if ops, ok := v.(*[]*Operation); ok {
+		decoded, err := DecodeOperations(r)
+		if err != nil {
+			return err
+		}
+		*ops = decoded
+		return nil
+	}
+	_, err := decodeJSONAPI(r, v, decodeOptions{})
+	return err
+}
+
+// DecodePartial decodes a JSON:API document into `v`, a struct pointer, like DecodeJSONAPI
+// and returns the attributes and relationships present in the document, see ApplyPresent
+func DecodePartial(r io.Reader, v interface{}) (*PresentFields, error) {
+	defer io.Copy(ioutil.Discard, r)
+	return decodeJSONAPI(r, v, decodeOptions{})
+}
+
+// decodeRequest decodes the JSON:API request body into `v` with the decoding options of the request
+func decodeRequest(r *http.Request, v interface{}) (*PresentFields, error) {
+	defer io.Copy(ioutil.Discard, r.Body)
+	opts := decodeOptions{}
+	if r.Method == http.MethodPost {
+		opts.clientIDs = getClientIDPolicy(r.Context())
+	}
+	opts.strict = isStrict(r.Context())
+	if param := getIDParam(r.Context()); param != "" {
+		opts.id = chi.URLParam(r, param)
+		opts.requireID = r.Method == http.MethodPatch && opts.id != ""
+	}
+	return decodeJSONAPI(r.Body, v, opts)
+}
+
+// decodeOptions holds the options of decodeJSONAPI
+type decodeOptions struct {
+	// lids resolves local ids of resources from other documents, e.g. previous atomic operations
+	lids LocalIDs
+	// clientIDs is the client-generated id policy of the primary data, if any
+	clientIDs *ClientIDPolicy
+	// id is the id the primary data must have, if any
+	id string
+	// requireID reports primary data without an `id`
+	requireID bool
+	// strict reports unknown members of the document
+	strict bool
+}
+
+// checkPrimaryData checks the `type` of the primary data of the document `body` against the `primary` tag of `v`,
+// and its `id` against `opts`
+func checkPrimaryData(body []byte, v interface{}, opts decodeOptions) error {
+	var doc struct {
+		Data *struct {
+			Type interface{} `json:"type"`
+			ID   interface{} `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil || doc.Data == nil {
+		// let the JSON:API decoder report malformed documents
+		return nil
+	}
+
+	if doc.Data.Type == nil || doc.Data.Type == "" {
+		return &DecodeError{Pointer: "/data/type", Code: "missing_type", Err: errors.New("missing resource type")}
+	}
+	typ, _ := doc.Data.Type.(string)
+	if res := resource.Of(reflect.TypeOf(v)); res != nil && typ != "" && typ != res.Name {
+		return &ConflictError{Member: "type", Expected: res.Name, Actual: typ}
+	}
+	id, ok := doc.Data.ID.(string)
+	switch {
+	case doc.Data.ID != nil && !ok:
+		// let checkDocument report ids that are not strings
+		return nil
+	case opts.requireID && id == "":
+		return &DecodeError{Pointer: "/data/id", Code: "missing_id", Err: errors.New("missing resource id")}
+	case opts.id != "" && id != "" && id != opts.id:
+		return &ConflictError{Member: "id", Expected: opts.id, Actual: id}
+	}
+	if opts.clientIDs != nil {
+		return opts.clientIDs.check(id)
+	}
+	return nil
+}
+
+// decodeJSONAPI decodes a JSON:API document into `v` as per `opts`,
+// it returns the attributes and relationships present in the document
+func decodeJSONAPI(r io.Reader, v interface{}, opts decodeOptions) (*PresentFields, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPrimaryData(body, v, opts); err != nil {
+		return nil, err
+	}
+	body, lid, err := resolveLocalIDs(body, opts.lids)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDocument(body, v, opts.strict); err != nil {
+		return nil, err
+	}
+	if err := jsonapi.UnmarshalPayload(bytes.NewReader(body), v); err != nil {
+		return nil, decodeError(err)
+	}
+	if err := setLocalResources(body, v); err != nil {
+		return nil, err
+	}
+	if l, ok := v.(LocalIdentifiable); ok && lid != "" {
+		l.SetJSONAPILID(lid)
+	}
+	return presentFields(body), nil
 }
@@ -0,0 +1,221 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/google/jsonapi"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+var (
+	// MetaCtxKey is the context key holding the top-level meta set with Meta
+	MetaCtxKey = &contextKey{"Meta"}
+	// LinksCtxKey is the context key holding the top-level links set with Links
+	LinksCtxKey = &contextKey{"Links"}
+)
+
+// JSONAPIVersion, when set (e.g. "1.1"), is rendered in the top-level `jsonapi` object of payloads
+// along with the extensions and profiles negotiated by Negotiate
+var JSONAPIVersion string
+
+// JSONAPIObject is the top-level `jsonapi` member, see https://jsonapi.org/format/#document-jsonapi-object
+type JSONAPIObject struct {
+	Version string   `json:"version,omitempty"`
+	Ext     []string `json:"ext,omitempty"`
+	Profile []string `json:"profile,omitempty"`
+}
+
+// document is a JSON:API top-level document holding a payload
+type document struct {
+	Data     interface{}     `json:"data"`
+	Included []*jsonapi.Node `json:"included,omitempty"`
+	Links    *jsonapi.Links  `json:"links,omitempty"`
+	Meta     *jsonapi.Meta   `json:"meta,omitempty"`
+	JSONAPI  *JSONAPIObject  `json:"jsonapi,omitempty"`
+
+	// lids holds the local ids rendered along with resource ids, keyed by node key
+	lids map[string]string
+}
+
+// Meta sets top-level meta members of the response document, e.g. a total count,
+// members are merged with the ones previously set and the ones of Metable payloads
+func Meta(r *http.Request, meta jsonapi.Meta) {
+	merged := jsonapi.Meta{}
+	for k, v := range getMeta(r.Context()) {
+		merged[k] = v
+	}
+	for k, v := range meta {
+		merged[k] = v
+	}
+	*r = *r.WithContext(context.WithValue(r.Context(), MetaCtxKey, merged))
+}
+
+// Links sets top-level links of the response document, e.g. `self`,
+// links are merged with the ones previously set and the ones of Linkable payloads
+func Links(r *http.Request, links jsonapi.Links) {
+	merged := jsonapi.Links{}
+	for k, v := range getLinks(r.Context()) {
+		merged[k] = v
+	}
+	for k, v := range links {
+		merged[k] = v
+	}
+	*r = *r.WithContext(context.WithValue(r.Context(), LinksCtxKey, merged))
+}
+
+func getMeta(ctx context.Context) jsonapi.Meta {
+	meta, _ := ctx.Value(MetaCtxKey).(jsonapi.Meta)
+	return meta
+}
+
+func getLinks(ctx context.Context) jsonapi.Links {
+	links, _ := ctx.Value(LinksCtxKey).(jsonapi.Links)
+	return links
+}
+
+// newDocument marshals `v`, a struct pointer or a slice of struct pointers, into a document
+// holding the top-level members set for the request, with includes and sparse fieldsets applied
+func newDocument(r *http.Request, v interface{}) (*document, error) {
+	if err := validateRequest(r, v); err != nil {
+		return nil, err
+	}
+
+	payload, err := jsonapi.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{}
+	switch p := payload.(type) {
+	case *jsonapi.OnePayload:
+		doc.Data, doc.Included, doc.Links, doc.Meta = p.Data, p.Included, p.Links, p.Meta
+	case *jsonapi.ManyPayload:
+		doc.Data, doc.Included, doc.Links, doc.Meta = p.Data, p.Included, p.Links, p.Meta
+	}
+
+	if include, ok := includePaths(r, v); ok {
+		doc.Included = includedNodes(include, doc.primary(), doc.Included)
+	}
+	applyFields(getFields(r.Context()), append(doc.primary(), doc.Included...))
+
+	data, collection := doc.Data.([]*jsonapi.Node)
+	doc.setTopLevel(r, collection, len(data))
+	doc.lids = localNodes(r, v, doc)
+
+	return doc, nil
+}
+
+// validateRequest validates the includes and sparse fieldsets set for the request against the resource of `v`
+func validateRequest(r *http.Request, v interface{}) error {
+	res := resource.Of(reflect.TypeOf(v))
+	if res == nil {
+		return nil
+	}
+	var errs Errors
+	if include, ok := getInclude(r.Context()); ok {
+		if err := validateInclude(include, res); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if fields := getFields(r.Context()); len(fields) > 0 {
+		if err := validateFields(fields, res); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// setTopLevel merges the top-level links and meta set for the request into the ones of the payload,
+// and sets the `jsonapi` object. Pagination links are only added to collections of `count` resources
+func (doc *document) setTopLevel(r *http.Request, collection bool, count int) {
+	links := jsonapi.Links{}
+	if collection {
+		if p := getPaginator(r.Context()); p != nil {
+			for k, v := range p.PaginationLinks(r.URL, count) {
+				links[k] = v
+			}
+		}
+	}
+	for k, v := range getLinks(r.Context()) {
+		links[k] = v
+	}
+	if len(links) > 0 {
+		merged := jsonapi.Links{}
+		if doc.Links != nil {
+			for k, v := range *doc.Links {
+				merged[k] = v
+			}
+		}
+		for k, v := range links {
+			merged[k] = v
+		}
+		doc.Links = &merged
+	}
+
+	if meta := getMeta(r.Context()); len(meta) > 0 {
+		merged := jsonapi.Meta{}
+		if doc.Meta != nil {
+			for k, v := range *doc.Meta {
+				merged[k] = v
+			}
+		}
+		for k, v := range meta {
+			merged[k] = v
+		}
+		doc.Meta = &merged
+	}
+
+	if JSONAPIVersion != "" {
+		params := GetMediaTypeParams(r)
+		doc.JSONAPI = &JSONAPIObject{Version: JSONAPIVersion, Ext: params.Ext, Profile: params.Profile}
+	}
+}
+
+// primary returns the resources of the primary data
+func (doc *document) primary() []*jsonapi.Node {
+	switch data := doc.Data.(type) {
+	case *jsonapi.Node:
+		if data != nil {
+			return []*jsonapi.Node{data}
+		}
+	case []*jsonapi.Node:
+		return data
+	}
+	return nil
+}
+
+func (doc *document) marshal(w io.Writer) error {
+	if len(doc.lids) == 0 {
+		return json.NewEncoder(w).Encode(doc)
+	}
+
+	// render resource objects with their local ids
+	var data interface{} = doc.Data
+	switch d := doc.Data.(type) {
+	case *jsonapi.Node:
+		if d != nil {
+			data = withLocalID(d, doc.lids)
+		}
+	case []*jsonapi.Node:
+		nodes := make([]interface{}, len(d))
+		for i, node := range d {
+			nodes[i] = withLocalID(node, doc.lids)
+		}
+		data = nodes
+	}
+	var included []interface{}
+	for _, node := range doc.Included {
+		included = append(included, withLocalID(node, doc.lids))
+	}
+	return json.NewEncoder(w).Encode(struct {
+		document
+		Data     interface{}   `json:"data"`
+		Included []interface{} `json:"included,omitempty"`
+	}{*doc, data, included})
+}
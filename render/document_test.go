@@ -0,0 +1,72 @@
+package render_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/google/jsonapi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// blogList is a Linkable and Metable collection
+type blogList []*Blog
+
+func (l blogList) JSONAPILinks() *jsonapi.Links {
+	return &jsonapi.Links{"self": "http://www.example.com/blogs", "related": "http://www.example.com/related"}
+}
+
+func (l blogList) JSONAPIMeta() *jsonapi.Meta {
+	return &jsonapi.Meta{"generated": true}
+}
+
+func TestJSONAPI_TopLevelMembers(t *testing.T) {
+	defer func(version string) { render.JSONAPIVersion = version }(render.JSONAPIVersion)
+
+	tests := []struct {
+		name         string
+		version      string
+		v            interface{}
+		meta         []jsonapi.Meta
+		links        jsonapi.Links
+		expectedBody []byte
+	}{
+		{
+			name:         "single payload with meta and links",
+			v:            &Blog{ID: 1, Title: "Blog"},
+			meta:         []jsonapi.Meta{{"total": 1}, {"pages": 1}},
+			links:        jsonapi.Links{"self": "http://www.example.com/blogs/1"},
+			expectedBody: []byte(`{"data":{"type":"blogs","id":"1","attributes":{"current_post_id":0,"title":"Blog","view_count":0},"relationships":{"current_post":{"data":null},"posts":{"data":[]}}},"links":{"self":"http://www.example.com/blogs/1"},"meta":{"pages":1,"total":1}}`),
+		},
+		{
+			name:         "collection payload with jsonapi object",
+			version:      "1.1",
+			v:            []*Blog{{ID: 1, Title: "Blog"}},
+			meta:         []jsonapi.Meta{{"total": 10}},
+			expectedBody: []byte(`{"data":[{"type":"blogs","id":"1","attributes":{"current_post_id":0,"title":"Blog","view_count":0},"relationships":{"current_post":{"data":null},"posts":{"data":[]}}}],"meta":{"total":10},"jsonapi":{"version":"1.1"}}`),
+		},
+		{
+			name:         "merged with linkable and metable collection",
+			v:            blogList{},
+			meta:         []jsonapi.Meta{{"total": 0}},
+			links:        jsonapi.Links{"self": "http://www.example.com/blogs?page[number]=1"},
+			expectedBody: []byte(`{"data":[],"links":{"related":"http://www.example.com/related","self":"http://www.example.com/blogs?page[number]=1"},"meta":{"generated":true,"total":0}}`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			render.JSONAPIVersion = test.version
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			for _, meta := range test.meta {
+				render.Meta(r, meta)
+			}
+			render.Links(r, test.links)
+			w := httptest.NewRecorder()
+			render.JSONAPI(w, r, test.v)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, string(test.expectedBody), strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
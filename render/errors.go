@@ -0,0 +1,213 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error is a JSON:API error object, see https://jsonapi.org/format/#error-objects
+// it can be returned as an error and rendered through JSONAPI
+type Error struct {
+	// ID is a unique identifier for this particular occurrence of the problem
+	ID string `json:"id,omitempty"`
+	// Links holds links to further details about the problem
+	Links *ErrorLinks `json:"links,omitempty"`
+	// Title is a short summary of the problem, defaults to the HTTP status text
+	Title string `json:"title,omitempty"`
+	// Detail is an explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Status is the HTTP status code applicable to the problem
+	Status int `json:"status,string,omitempty"`
+	// Code is an application-specific error code
+	Code string `json:"code,omitempty"`
+	// Source holds references to the primary source of the problem
+	Source *ErrorSource `json:"source,omitempty"`
+	// Meta holds non-standard meta-information about the problem
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// ErrorLinks is the `links` member of an error object
+type ErrorLinks struct {
+	About string `json:"about,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// ErrorSource is the `source` member of an error object
+type ErrorSource struct {
+	// Pointer is a JSON Pointer to the value in the request document that caused the error, e.g. `/data/attributes/title`
+	Pointer string `json:"pointer,omitempty"`
+	// Parameter is the query parameter that caused the error
+	Parameter string `json:"parameter,omitempty"`
+	// Header is the request header that caused the error
+	Header string `json:"header,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	if e.Title != "" {
+		return e.Title
+	}
+	return http.StatusText(e.Status)
+}
+
+// JSONAPIError implements ErrorObjecter
+func (e *Error) JSONAPIError() *Error {
+	return e
+}
+
+// StatusCode implements StatusCoder
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+// ErrorObjecter is implemented by errors that render as a specific JSON:API error object,
+// it is detected anywhere in the chain of wrapped errors
+type ErrorObjecter interface {
+	JSONAPIError() *Error
+}
+
+// StatusCoder is implemented by errors carrying their own HTTP status,
+// it is detected anywhere in the chain of wrapped errors
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// StatusMapper returns the HTTP status of errors that neither are error objects with a status nor implement
+// StatusCoder, or 0 if unknown. Replace it to map sentinel errors, e.g. `sql.ErrNoRows` to 404 Not Found
+var StatusMapper = DefaultStatusMapper
+
+// DefaultStatusMapper maps context.DeadlineExceeded to 504 Gateway Timeout
+func DefaultStatusMapper(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return 0
+}
+
+// Errors is a collection of errors rendered as a single JSON:API error document, one error object per entry
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the collected errors
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// errorsPayload is a JSON:API document holding errors
+type errorsPayload struct {
+	Errors []*Error `json:"errors"`
+}
+
+// asErrorObject returns the JSON:API error object of `err` if any ErrorObjecter is found in its chain
+func asErrorObject(err error) (*Error, bool) {
+	var objecter ErrorObjecter
+	if !errors.As(err, &objecter) {
+		return nil, false
+	}
+	obj := objecter.JSONAPIError()
+	return obj, obj != nil
+}
+
+// flattenErrors expands multi-errors, i.e. errors with an `Unwrap() []error` method such as Errors
+// or the ones returned by errors.Join, unless they render as an error object themselves
+func flattenErrors(err error) (errs []error) {
+	if _, ok := err.(ErrorObjecter); ok {
+		return []error{err}
+	}
+	multi, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	for _, e := range multi.Unwrap() {
+		if e != nil {
+			errs = append(errs, flattenErrors(e)...)
+		}
+	}
+	return errs
+}
+
+// errorStatus returns the HTTP status of `err` from its error object, StatusCoder or StatusMapper, or 0 if unknown
+func errorStatus(err error) int {
+	if obj, ok := asErrorObject(err); ok && obj.Status != 0 {
+		return obj.Status
+	}
+	var coder StatusCoder
+	if errors.As(err, &coder) && coder.StatusCode() != 0 {
+		return coder.StatusCode()
+	}
+	if StatusMapper != nil {
+		return StatusMapper(err)
+	}
+	return 0
+}
+
+// errorsStatus returns the most generally applicable HTTP status for `errs`: their status if they all share it,
+// 400 Bad Request if they are all 4xx, 500 Internal Server Error otherwise.
+// Errors without a known status count as 500 Internal Server Error
+func errorsStatus(errs []error) int {
+	status := 0
+	for _, err := range errs {
+		s := errorStatus(err)
+		if s == 0 {
+			s = http.StatusInternalServerError
+		}
+		switch {
+		case status == 0 || status == s:
+			status = s
+		case status/100 == 4 && s/100 == 4:
+			status = http.StatusBadRequest
+		default:
+			return http.StatusInternalServerError
+		}
+	}
+	if status == 0 {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// toJSONAPIErrors converts errors into JSON:API error objects, `status` is used unless the error has its own.
+// When `explicit`, i.e. set with render.Status, `status` is also used instead of the status derived from
+// StatusCoder or StatusMapper, so that error objects agree with the response status.
+// An empty collection yields a generic error object so that the error document is valid
+func toJSONAPIErrors(status int, explicit bool, errs ...error) (jsonapierrors []*Error) {
+	if len(errs) == 0 {
+		return []*Error{{Title: http.StatusText(status), Status: status}}
+	}
+	for _, e := range errs {
+		obj := &Error{Detail: e.Error()}
+		if o, ok := asErrorObject(e); ok {
+			// copy so that defaults are not written back into the caller's error
+			c := *o
+			obj = &c
+		}
+		if obj.Status == 0 && !explicit {
+			obj.Status = errorStatus(e)
+		}
+		if obj.Status == 0 {
+			obj.Status = status
+		}
+		if obj.Title == "" {
+			obj.Title = http.StatusText(obj.Status)
+		}
+		jsonapierrors = append(jsonapierrors, obj)
+	}
+	return
+}
+
+func marshalErrors(w io.Writer, errs []*Error) error {
+	return json.NewEncoder(w).Encode(&errorsPayload{Errors: errs})
+}
@@ -0,0 +1,294 @@
+package render_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	chi_render "github.com/go-chi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// notFoundError is a custom error rendered as a JSON:API error object
+type notFoundError struct {
+	id string
+}
+
+func (e notFoundError) Error() string {
+	return "blog " + e.id + " not found"
+}
+
+func (e notFoundError) JSONAPIError() *render.Error {
+	return &render.Error{Status: http.StatusNotFound, Code: "blog_not_found", Detail: e.Error()}
+}
+
+func TestJSONAPI_ErrorObjects(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		err            error
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{
+			name: "error object",
+			err: &render.Error{
+				ID:     "1",
+				Links:  &render.ErrorLinks{About: "https://example.com/errors/1"},
+				Status: http.StatusUnprocessableEntity,
+				Code:   "invalid_title",
+				Detail: "title is too long",
+				Source: &render.ErrorSource{Pointer: "/data/attributes/title"},
+				Meta:   map[string]interface{}{"max": 10},
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   []byte(`{"errors":[{"id":"1","links":{"about":"https://example.com/errors/1"},"title":"Unprocessable Entity","detail":"title is too long","status":"422","code":"invalid_title","source":{"pointer":"/data/attributes/title"},"meta":{"max":10}}]}`),
+		},
+		{
+			name:           "wrapped error object",
+			err:            fmt.Errorf("creating blog: %w", &render.Error{Status: http.StatusConflict, Title: "Duplicated", Source: &render.ErrorSource{Parameter: "id"}}),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   []byte(`{"errors":[{"title":"Duplicated","status":"409","source":{"parameter":"id"}}]}`),
+		},
+		{
+			name:           "custom error implementing ErrorObjecter",
+			err:            fmt.Errorf("loading blog: %w", notFoundError{id: "42"}),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   []byte(`{"errors":[{"title":"Not Found","detail":"blog 42 not found","status":"404","code":"blog_not_found"}]}`),
+		},
+		{
+			name:           "error object without status takes the response status",
+			status:         http.StatusBadRequest,
+			err:            &render.Error{Code: "bad"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []byte(`{"errors":[{"title":"Bad Request","status":"400","code":"bad"}]}`),
+		},
+		{
+			name:           "plain error",
+			err:            errors.New("something went wrong"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   []byte(`{"errors":[{"title":"Internal Server Error","detail":"something went wrong","status":"500"}]}`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			r = r.WithContext(context.WithValue(r.Context(), chi_render.ContentTypeCtxKey, render.ContentTypeJSONAPI))
+			if test.status != 0 {
+				chi_render.Status(r, test.status)
+			}
+			w := httptest.NewRecorder()
+			render.DefaultResponder(w, r, test.err)
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, string(test.expectedBody), strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+func TestError_Error(t *testing.T) {
+	assert.Equal(t, "title is too long", (&render.Error{Title: "Invalid", Detail: "title is too long"}).Error())
+	assert.Equal(t, "Invalid", (&render.Error{Title: "Invalid"}).Error())
+	assert.Equal(t, "Not Found", (&render.Error{Status: http.StatusNotFound}).Error())
+}
+
+// joinedError mimics the multi-errors returned by errors.Join
+type joinedError struct {
+	errs []error
+}
+
+func (e joinedError) Error() string {
+	return "joined"
+}
+
+func (e joinedError) Unwrap() []error {
+	return e.errs
+}
+
+func TestJSONAPI_MultipleErrors(t *testing.T) {
+	titleErr := &render.Error{Status: http.StatusUnprocessableEntity, Detail: "title is required", Source: &render.ErrorSource{Pointer: "/data/attributes/title"}}
+	viewCountErr := &render.Error{Status: http.StatusUnprocessableEntity, Detail: "view_count must be positive", Source: &render.ErrorSource{Pointer: "/data/attributes/view_count"}}
+	forbiddenErr := &render.Error{Status: http.StatusForbidden, Detail: "not allowed"}
+
+	tests := []struct {
+		name           string
+		status         int
+		v              interface{}
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{
+			name:           "errors collection sharing status",
+			v:              render.Errors{titleErr, viewCountErr},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   []byte(`{"errors":[{"title":"Unprocessable Entity","detail":"title is required","status":"422","source":{"pointer":"/data/attributes/title"}},{"title":"Unprocessable Entity","detail":"view_count must be positive","status":"422","source":{"pointer":"/data/attributes/view_count"}}]}`),
+		},
+		{
+			name:           "slice of errors with different 4xx statuses",
+			v:              []error{titleErr, forbiddenErr},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []byte(`{"errors":[{"title":"Unprocessable Entity","detail":"title is required","status":"422","source":{"pointer":"/data/attributes/title"}},{"title":"Forbidden","detail":"not allowed","status":"403"}]}`),
+		},
+		{
+			name:           "joined errors with 4xx and 5xx statuses",
+			v:              joinedError{errs: []error{titleErr, errors.New("database is down")}},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   []byte(`{"errors":[{"title":"Unprocessable Entity","detail":"title is required","status":"422","source":{"pointer":"/data/attributes/title"}},{"title":"Internal Server Error","detail":"database is down","status":"500"}]}`),
+		},
+		{
+			name:           "nested multi-errors are flattened",
+			v:              render.Errors{joinedError{errs: []error{titleErr, nil}}, viewCountErr},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   []byte(`{"errors":[{"title":"Unprocessable Entity","detail":"title is required","status":"422","source":{"pointer":"/data/attributes/title"}},{"title":"Unprocessable Entity","detail":"view_count must be positive","status":"422","source":{"pointer":"/data/attributes/view_count"}}]}`),
+		},
+		{
+			name:           "empty errors collection renders a generic error object",
+			v:              render.Errors{},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   []byte(`{"errors":[{"title":"Internal Server Error","status":"500"}]}`),
+		},
+		{
+			name:           "errors collection of nil errors renders a generic error object",
+			status:         http.StatusBadRequest,
+			v:              render.Errors{nil},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []byte(`{"errors":[{"title":"Bad Request","status":"400"}]}`),
+		},
+		{
+			name:           "explicit status wins",
+			status:         http.StatusConflict,
+			v:              []error{errors.New("a"), errors.New("b")},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   []byte(`{"errors":[{"title":"Conflict","detail":"a","status":"409"},{"title":"Conflict","detail":"b","status":"409"}]}`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			if test.status != 0 {
+				chi_render.Status(r, test.status)
+			}
+			w := httptest.NewRecorder()
+			render.JSONAPI(w, r, test.v)
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, string(test.expectedBody), strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+func TestErrors_Error(t *testing.T) {
+	assert.Equal(t, "a; b", render.Errors{errors.New("a"), errors.New("b")}.Error())
+}
+
+// conflictError is a custom error carrying its own HTTP status
+type conflictError struct{}
+
+func (e conflictError) Error() string {
+	return "blog already exists"
+}
+
+func (e conflictError) StatusCode() int {
+	return http.StatusConflict
+}
+
+func TestJSONAPI_ErrorStatus(t *testing.T) {
+	defer func(mapper func(error) int) { render.StatusMapper = mapper }(render.StatusMapper)
+	render.StatusMapper = func(err error) int {
+		if errors.Is(err, sql.ErrNoRows) {
+			return http.StatusNotFound
+		}
+		return render.DefaultStatusMapper(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+
+	tests := []struct {
+		name           string
+		status         int
+		err            error
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{
+			name:           "error implementing StatusCoder",
+			err:            fmt.Errorf("creating blog: %w", conflictError{}),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   []byte(`{"errors":[{"title":"Conflict","detail":"creating blog: blog already exists","status":"409"}]}`),
+		},
+		{
+			name:           "sentinel error mapped by StatusMapper",
+			err:            fmt.Errorf("loading blog: %w", sql.ErrNoRows),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   []byte(`{"errors":[{"title":"Not Found","detail":"loading blog: sql: no rows in result set","status":"404"}]}`),
+		},
+		{
+			name:           "context deadline mapped by DefaultStatusMapper",
+			err:            ctx.Err(),
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   []byte(`{"errors":[{"title":"Gateway Timeout","detail":"context deadline exceeded","status":"504"}]}`),
+		},
+		{
+			name:           "explicit status wins",
+			status:         http.StatusUnprocessableEntity,
+			err:            errors.New("something went wrong"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   []byte(`{"errors":[{"title":"Unprocessable Entity","detail":"something went wrong","status":"422"}]}`),
+		},
+		{
+			name:           "explicit status wins over derived statuses",
+			status:         http.StatusUnprocessableEntity,
+			err:            render.Errors{conflictError{}, ctx.Err(), &render.Error{Status: http.StatusForbidden, Detail: "not allowed"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   []byte(`{"errors":[{"title":"Unprocessable Entity","detail":"blog already exists","status":"422"},{"title":"Unprocessable Entity","detail":"context deadline exceeded","status":"422"},{"title":"Forbidden","detail":"not allowed","status":"403"}]}`),
+		},
+		{
+			name:           "unknown errors default to 500",
+			err:            errors.New("something went wrong"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   []byte(`{"errors":[{"title":"Internal Server Error","detail":"something went wrong","status":"500"}]}`),
+		},
+		{
+			name:           "multiple errors with own statuses",
+			err:            render.Errors{conflictError{}, sql.ErrNoRows},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []byte(`{"errors":[{"title":"Conflict","detail":"blog already exists","status":"409"},{"title":"Not Found","detail":"sql: no rows in result set","status":"404"}]}`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			if test.status != 0 {
+				chi_render.Status(r, test.status)
+			}
+			w := httptest.NewRecorder()
+			render.JSONAPI(w, r, test.err)
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, string(test.expectedBody), strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+// errorObjects returns the error objects of `err`, a single error or Errors
+func errorObjects(t *testing.T, err error) []*render.Error {
+	var errs render.Errors
+	if !errors.As(err, &errs) {
+		errs = render.Errors{err}
+	}
+	var objs []*render.Error
+	for _, e := range errs {
+		var objecter render.ErrorObjecter
+		if assert.True(t, errors.As(e, &objecter), e.Error()) {
+			objs = append(objs, objecter.JSONAPIError())
+		}
+	}
+	return objs
+}
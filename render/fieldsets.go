@@ -0,0 +1,89 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/google/jsonapi"
+	"net/http"
+	"sort"
+)
+
+// FieldsCtxKey is the context key holding the sparse fieldsets set with Fields
+var FieldsCtxKey = &contextKey{"Fields"}
+
+// Fields sets the sparse fieldsets, lists of attributes and relationships keyed by resource type, that JSONAPI applies
+// to primary data and included resources, see https://jsonapi.org/format/#fetching-sparse-fieldsets.
+// queryparams.Middleware sets them from the `fields[TYPE]` query parameters
+func Fields(r *http.Request, fields map[string][]string) {
+	*r = *r.WithContext(context.WithValue(r.Context(), FieldsCtxKey, fields))
+}
+
+func getFields(ctx context.Context) map[string][]string {
+	fields, _ := ctx.Value(FieldsCtxKey).(map[string][]string)
+	return fields
+}
+
+// validateFields returns a 400 Bad Request error for each type of `fields` not reachable from `res`,
+// and for each field that is neither an attribute nor a relationship of its type
+func validateFields(fields map[string][]string, res *resource.Resource) error {
+	types := make([]string, 0, len(fields))
+	for typ := range fields {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	var errs Errors
+	reachable := res.Reachable()
+	for _, typ := range types {
+		typeRes, ok := reachable[typ]
+		if !ok {
+			errs = append(errs, newFieldsError(typ, fmt.Sprintf("unknown resource type %q", typ)))
+			continue
+		}
+		for _, name := range fields[typ] {
+			if typeRes.Attribute(name) == nil && typeRes.Relationship(name) == nil {
+				errs = append(errs, newFieldsError(typ, fmt.Sprintf("unknown field %q of resource type %q", name, typ)))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func newFieldsError(typ, detail string) error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Code:   "invalid_sparse_fieldset",
+		Detail: detail,
+		Source: &ErrorSource{Parameter: "fields[" + typ + "]"},
+	}
+}
+
+// applyFields removes the attributes and relationships of `nodes` missing from the fieldset of their type
+func applyFields(fields map[string][]string, nodes []*jsonapi.Node) {
+	for _, node := range nodes {
+		fieldset, ok := fields[node.Type]
+		if !ok {
+			continue
+		}
+		for name := range node.Attributes {
+			if !contains(fieldset, name) {
+				delete(node.Attributes, name)
+			}
+		}
+		for name := range node.Relationships {
+			if !contains(fieldset, name) {
+				delete(node.Relationships, name)
+			}
+		}
+		if len(node.Attributes) == 0 {
+			node.Attributes = nil
+		}
+		if len(node.Relationships) == 0 {
+			node.Relationships = nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package render_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONAPI_SparseFieldsets(t *testing.T) {
+	blog := &Blog{ID: 1, Title: "Blog", ViewCount: 3, Posts: []*Post{{ID: 2, Title: "Post", Body: "Body"}}}
+
+	tests := []struct {
+		name           string
+		fields         map[string][]string
+		v              interface{}
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{
+			name:           "primary data and included resources",
+			fields:         map[string][]string{"blogs": {"title", "posts"}, "posts": {"title"}},
+			v:              blog,
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte(`{"data":{"type":"blogs","id":"1","attributes":{"title":"Blog"},"relationships":{"posts":{"data":[{"type":"posts","id":"2"}]}}},"included":[{"type":"posts","id":"2","attributes":{"title":"Post"}}]}`),
+		},
+		{
+			name:           "empty fieldset",
+			fields:         map[string][]string{"blogs": {}},
+			v:              []*Blog{{ID: 1}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte(`{"data":[{"type":"blogs","id":"1"}]}`),
+		},
+		{
+			name:           "types without fieldset are left untouched",
+			fields:         map[string][]string{"comments": {"body"}},
+			v:              &Blog{ID: 1},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte(`{"data":{"type":"blogs","id":"1","attributes":{"current_post_id":0,"title":"","view_count":0},"relationships":{"current_post":{"data":null},"posts":{"data":[]}}}}`),
+		},
+		{
+			name:           "unknown type and fields",
+			fields:         map[string][]string{"blogs": {"titel"}, "authors": {"name"}, "posts": {"title", "comments"}},
+			v:              blog,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []byte(`{"errors":[{"title":"Bad Request","detail":"unknown resource type \"authors\"","status":"400","code":"invalid_sparse_fieldset","source":{"parameter":"fields[authors]"}},{"title":"Bad Request","detail":"unknown field \"titel\" of resource type \"blogs\"","status":"400","code":"invalid_sparse_fieldset","source":{"parameter":"fields[blogs]"}}]}`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			render.Fields(r, test.fields)
+			w := httptest.NewRecorder()
+			render.JSONAPI(w, r, test.v)
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, string(test.expectedBody), strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
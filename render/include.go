@@ -0,0 +1,160 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/google/jsonapi"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// IncludeCtxKey is the context key holding the relationship paths set with Include
+var IncludeCtxKey = &contextKey{"Include"}
+
+// Includable is implemented by resources restricting the relationship paths clients can include,
+// e.g. `posts.comments` (which also allows `posts`). By default every relationship path is includable.
+// Only the includable paths are included when Include is not set
+type Includable interface {
+	JSONAPIIncludable() []string
+}
+
+// Include sets the relationship paths (e.g. `posts.comments`) of the resources JSONAPI includes in compound
+// documents, see https://jsonapi.org/format/#fetching-includes. Relationships not in a path only render linkage.
+// When not set every related resource is included, or the ones of the paths of Includable resources. queryparams.Middleware sets them from the `include` query parameter
+func Include(r *http.Request, paths []string) {
+	if paths == nil {
+		paths = []string{}
+	}
+	*r = *r.WithContext(context.WithValue(r.Context(), IncludeCtxKey, paths))
+}
+
+func getInclude(ctx context.Context) ([]string, bool) {
+	paths, ok := ctx.Value(IncludeCtxKey).([]string)
+	return paths, ok
+}
+
+// includePaths returns the relationship paths to include in the document of `v`, the ones set with Include
+// or else the includable paths of `v`. It returns false when every related resource is to be included
+func includePaths(r *http.Request, v interface{}) ([]string, bool) {
+	if paths, ok := getInclude(r.Context()); ok {
+		return paths, true
+	}
+	if res := resource.Of(reflect.TypeOf(v)); res != nil {
+		if includable := includablePaths(res); includable != nil {
+			return includable, true
+		}
+	}
+	return nil, false
+}
+
+// includablePaths returns the includable paths of `res` if it is Includable, or nil
+func includablePaths(res *resource.Resource) []string {
+	if i, ok := reflect.New(res.Type).Interface().(Includable); ok {
+		return i.JSONAPIIncludable()
+	}
+	return nil
+}
+
+// validateInclude returns a 400 Bad Request error for each path that is not made of relationships of `res`,
+// or that is not includable as per Includable
+func validateInclude(paths []string, res *resource.Resource) error {
+	includable := includablePaths(res)
+	var errs Errors
+	for _, path := range paths {
+		if !isRelationshipPath(path, res) {
+			errs = append(errs, newIncludeError(fmt.Sprintf("unknown relationship path %q", path)))
+			continue
+		}
+		if includable != nil && !isIncludable(path, includable) {
+			errs = append(errs, newIncludeError(fmt.Sprintf("relationship path %q is not includable", path)))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func isRelationshipPath(path string, res *resource.Resource) bool {
+	for _, name := range strings.Split(path, ".") {
+		rel := res.Relationship(name)
+		if rel == nil {
+			return false
+		}
+		if res = rel.Related(); res == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isIncludable reports whether `path` is one of `includable` or a prefix of one
+func isIncludable(path string, includable []string) bool {
+	for _, p := range includable {
+		if p == path || strings.HasPrefix(p, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func newIncludeError(detail string) error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Code:   "invalid_include",
+		Detail: detail,
+		Source: &ErrorSource{Parameter: "include"},
+	}
+}
+
+// includedNodes returns the resources of `included` reachable from `primary` through `paths`
+func includedNodes(paths []string, primary, included []*jsonapi.Node) []*jsonapi.Node {
+	index := map[string]*jsonapi.Node{}
+	for _, node := range included {
+		index[nodeKey(node)] = node
+	}
+
+	keep := map[string]bool{}
+	for _, path := range paths {
+		nodes := primary
+		for _, name := range strings.Split(path, ".") {
+			var next []*jsonapi.Node
+			for _, node := range nodes {
+				for _, linkage := range relationshipLinkage(node, name) {
+					if n, ok := index[nodeKey(linkage)]; ok {
+						keep[nodeKey(n)] = true
+						next = append(next, n)
+					}
+				}
+			}
+			nodes = next
+		}
+	}
+
+	result := []*jsonapi.Node{}
+	for _, node := range included {
+		if keep[nodeKey(node)] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// relationshipLinkage returns the resource identifiers of the relationship `name` of `node`
+func relationshipLinkage(node *jsonapi.Node, name string) []*jsonapi.Node {
+	switch rel := node.Relationships[name].(type) {
+	case *jsonapi.RelationshipOneNode:
+		if rel.Data != nil {
+			return []*jsonapi.Node{rel.Data}
+		}
+	case *jsonapi.RelationshipManyNode:
+		return rel.Data
+	}
+	return nil
+}
+
+func nodeKey(node *jsonapi.Node) string {
+	return node.Type + "," + node.ID
+}
@@ -0,0 +1,120 @@
+package render_test
+
+import (
+	"encoding/json"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type Author struct {
+	ID    int     `jsonapi:"primary,authors"`
+	Name  string  `jsonapi:"attr,name"`
+	Blogs []*Blog `jsonapi:"relation,blogs"`
+}
+
+// JSONAPIIncludable restricts includes to blogs and their current post
+func (a *Author) JSONAPIIncludable() []string {
+	return []string{"blogs.current_post"}
+}
+
+// includedKeys returns the sorted `type,id` keys of the included resources of a document
+func includedKeys(t *testing.T, body []byte) []string {
+	var doc struct {
+		Included []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"included"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	keys := []string{}
+	for _, n := range doc.Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestJSONAPI_Include(t *testing.T) {
+	post := &Post{ID: 2, Title: "Post", Comments: []*Comment{{ID: 3, Body: "Comment"}}}
+	blog := &Blog{ID: 1, Title: "Blog", Posts: []*Post{post}, CurrentPost: &Post{ID: 4}}
+
+	tests := []struct {
+		name             string
+		include          []string
+		v                interface{}
+		expectedIncluded []string
+	}{
+		{
+			name:             "include not set includes everything",
+			v:                blog,
+			expectedIncluded: []string{"comments,3", "posts,2", "posts,4"},
+		},
+		{
+			name:             "empty include",
+			include:          []string{},
+			v:                blog,
+			expectedIncluded: []string{},
+		},
+		{
+			name:             "single relationship",
+			include:          []string{"posts"},
+			v:                blog,
+			expectedIncluded: []string{"posts,2"},
+		},
+		{
+			name:             "relationship path",
+			include:          []string{"posts.comments"},
+			v:                []*Blog{blog},
+			expectedIncluded: []string{"comments,3", "posts,2"},
+		},
+		{
+			name:             "include not set includes the includable paths",
+			v:                &Author{ID: 5, Blogs: []*Blog{blog}},
+			expectedIncluded: []string{"blogs,1", "posts,4"},
+		},
+		{
+			name:             "includable path prefix",
+			include:          []string{"blogs"},
+			v:                &Author{ID: 5, Blogs: []*Blog{blog}},
+			expectedIncluded: []string{"blogs,1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			if test.include != nil {
+				render.Include(r, test.include)
+			}
+			w := httptest.NewRecorder()
+			render.JSONAPI(w, r, test.v)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, test.expectedIncluded, includedKeys(t, w.Body.Bytes()))
+		})
+	}
+
+	t.Run("should keep linkage of relationships not included", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		render.Include(r, []string{})
+		w := httptest.NewRecorder()
+		render.JSONAPI(w, r, &Blog{ID: 1, Posts: []*Post{{ID: 2}}})
+		assert.Equal(t, `{"data":{"type":"blogs","id":"1","attributes":{"current_post_id":0,"title":"","view_count":0},"relationships":{"current_post":{"data":null},"posts":{"data":[{"type":"posts","id":"2"}]}}}}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should respond 400 on unsupported paths", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		render.Include(r, []string{"blogs.posts", "blogs.titel"})
+		w := httptest.NewRecorder()
+		render.JSONAPI(w, r, &Author{ID: 5})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"relationship path \"blogs.posts\" is not includable","status":"400","code":"invalid_include","source":{"parameter":"include"}},{"title":"Bad Request","detail":"unknown relationship path \"blogs.titel\"","status":"400","code":"invalid_include","source":{"parameter":"include"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+}
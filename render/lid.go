@@ -0,0 +1,316 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/google/jsonapi"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// LocalIDsCtxKey is the context key holding the local ids set with LocalID
+var LocalIDsCtxKey = &contextKey{"LocalIDs"}
+
+// LocalIdentifiable is implemented by resources keeping the local id (`lid`) they were created with,
+// DecodeJSONAPI sets it and JSONAPI renders it along with the id generated by the server
+type LocalIdentifiable interface {
+	JSONAPILID() string
+	SetJSONAPILID(lid string)
+}
+
+// LocalIDs maps the local ids (`lid`) of resources to their ids, keyed by type and local id
+type LocalIDs map[string]string
+
+// Get returns the id of the resource of type `typ` with the local id `lid`
+func (l LocalIDs) Get(typ, lid string) (string, bool) {
+	id, ok := l[typ+","+lid]
+	return id, ok
+}
+
+// Set maps the local id `lid` of the resource of type `typ` to `id`
+func (l LocalIDs) Set(typ, lid, id string) {
+	l[typ+","+lid] = id
+}
+
+// LocalID maps the local id `lid` of a resource of type `typ`, created by the request, to the `id` generated
+// by the server so that JSONAPI renders the resource with both its `id` and `lid`
+func LocalID(r *http.Request, typ, lid, id string) {
+	merged := map[string]string{}
+	for k, v := range getLocalIDs(r.Context()) {
+		merged[k] = v
+	}
+	merged[typ+","+id] = lid
+	*r = *r.WithContext(context.WithValue(r.Context(), LocalIDsCtxKey, merged))
+}
+
+// getLocalIDs returns the local ids set with LocalID keyed by resource type and id
+func getLocalIDs(ctx context.Context) map[string]string {
+	lids, _ := ctx.Value(LocalIDsCtxKey).(map[string]string)
+	return lids
+}
+
+// resolveLocalIDs resolves the `lid` of the resource identifiers of the relationships of the primary data
+// and included resources of the document `body` with `lids` and the resources of the document having both
+// an `id` and a `lid`.
+// Local ids of resources of the document without an `id` are left unresolved, see setLocalResources, other ones
+// are 400 Bad Request errors. It returns the document with resolved ids and the local id of the primary data, if any
+func resolveLocalIDs(body []byte, lids LocalIDs) ([]byte, string, error) {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		// let the JSON:API decoder report malformed documents
+		return body, "", nil
+	}
+
+	var objects []map[string]interface{}
+	var pointers []string
+	switch data := doc["data"].(type) {
+	case map[string]interface{}:
+		objects, pointers = append(objects, data), append(pointers, "/data")
+	case []interface{}:
+		for i, item := range data {
+			if object, ok := item.(map[string]interface{}); ok {
+				objects, pointers = append(objects, object), append(pointers, "/data/"+strconv.Itoa(i))
+			}
+		}
+	}
+	if len(objects) == 0 {
+		return body, "", nil
+	}
+	primary := objects[0]
+	included, _ := doc["included"].([]interface{})
+	for i, item := range included {
+		if object, ok := item.(map[string]interface{}); ok {
+			objects, pointers = append(objects, object), append(pointers, "/included/"+strconv.Itoa(i))
+		}
+	}
+
+	known := LocalIDs{}
+	for k, v := range lids {
+		known[k] = v
+	}
+	local := map[string]bool{}
+	for _, object := range objects {
+		typ, id, lid := identifierMembers(object)
+		if lid == "" {
+			continue
+		}
+		local[typ+","+lid] = true
+		if id != "" {
+			known.Set(typ, lid, id)
+		}
+	}
+
+	resolved := false
+	for i, object := range objects {
+		relationships, _ := object["relationships"].(map[string]interface{})
+		for name, rel := range relationships {
+			rel, _ := rel.(map[string]interface{})
+			pointer := pointers[i] + "/relationships/" + name + "/data"
+			var identifiers []map[string]interface{}
+			var identifierPointers []string
+			switch data := rel["data"].(type) {
+			case map[string]interface{}:
+				identifiers, identifierPointers = append(identifiers, data), append(identifierPointers, pointer)
+			case []interface{}:
+				for j, item := range data {
+					if identifier, ok := item.(map[string]interface{}); ok {
+						identifiers = append(identifiers, identifier)
+						identifierPointers = append(identifierPointers, pointer+"/"+strconv.Itoa(j))
+					}
+				}
+			}
+			for j, identifier := range identifiers {
+				typ, id, lid := identifierMembers(identifier)
+				if lid == "" || id != "" {
+					continue
+				}
+				if id, ok := known.Get(typ, lid); ok {
+					identifier["id"] = id
+					resolved = true
+					continue
+				}
+				if !local[typ+","+lid] {
+					return nil, "", &Error{
+						Status: http.StatusBadRequest,
+						Code:   "invalid_local_id",
+						Detail: fmt.Sprintf("unknown local id %q of type %q", lid, typ),
+						Source: &ErrorSource{Pointer: identifierPointers[j] + "/lid"},
+					}
+				}
+			}
+		}
+	}
+
+	_, _, lid := identifierMembers(primary)
+	if !resolved {
+		return body, lid, nil
+	}
+	resolvedBody, err := json.Marshal(doc)
+	return resolvedBody, lid, err
+}
+
+// identifierMembers returns the `type`, `id` and `lid` members of a resource object or identifier
+func identifierMembers(object map[string]interface{}) (typ, id, lid string) {
+	typ, _ = object["type"].(string)
+	id, _ = object["id"].(string)
+	lid, _ = object["lid"].(string)
+	return
+}
+
+// localResources holds the resources of a document having a local id, keyed by type and local id
+type localResources struct {
+	objects  map[string]map[string]interface{}
+	included []interface{}
+	visiting map[string]bool
+}
+
+// setLocalResources sets the related resources of `v`, the primary data of the document `body`, referenced by
+// a `lid` only with the resources of the document having that local id: google/jsonapi matches included resources
+// by type and id and would decode all of them as the same resource.
+// The local id of related resources implementing LocalIdentifiable is set
+func setLocalResources(body []byte, v interface{}) error {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil
+	}
+	primary, ok := doc["data"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	l := &localResources{objects: map[string]map[string]interface{}{}, visiting: map[string]bool{}}
+	l.included, _ = doc["included"].([]interface{})
+	for _, item := range append([]interface{}{primary}, l.included...) {
+		object, _ := item.(map[string]interface{})
+		if typ, _, lid := identifierMembers(object); lid != "" {
+			l.objects[typ+","+lid] = object
+		}
+	}
+	if len(l.objects) == 0 {
+		return nil
+	}
+	typ, _, lid := identifierMembers(primary)
+	l.visiting[typ+","+lid] = true
+	return l.link(primary, reflect.ValueOf(v))
+}
+
+// link sets the related resources of `rv`, decoded from `object`, referenced by local id
+func (l *localResources) link(object map[string]interface{}, rv reflect.Value) error {
+	res := resource.Of(rv.Type())
+	if res == nil || rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+	relationships, _ := object["relationships"].(map[string]interface{})
+	for _, rel := range res.Relationships {
+		related := rel.Related()
+		relationship, _ := relationships[rel.Name].(map[string]interface{})
+		if related == nil || relationship == nil {
+			continue
+		}
+		field := rv.Elem().Field(rel.Index)
+		identifiers, _ := relationship["data"].([]interface{})
+		if !rel.Many {
+			identifiers = []interface{}{relationship["data"]}
+		}
+		for i, identifier := range identifiers {
+			identifier, _ := identifier.(map[string]interface{})
+			typ, id, lid := identifierMembers(identifier)
+			if lid == "" || rel.Many && i >= field.Len() {
+				continue
+			}
+			target := field
+			if rel.Many {
+				target = field.Index(i)
+			}
+			if id == "" {
+				value, err := l.decode(typ, lid, related)
+				if err != nil {
+					return err
+				}
+				target.Set(value)
+			}
+			if local, ok := target.Interface().(LocalIdentifiable); ok && !target.IsNil() {
+				local.SetJSONAPILID(lid)
+			}
+		}
+	}
+	return nil
+}
+
+// decode returns a new resource of type `related` decoded from the resource of the document having the local id
+// `lid`, the resources being decoded are not decoded again so that reference cycles end
+func (l *localResources) decode(typ, lid string, related *resource.Resource) (reflect.Value, error) {
+	value := reflect.New(related.Type)
+	key := typ + "," + lid
+	object, ok := l.objects[key]
+	if !ok || l.visiting[key] {
+		return value, nil
+	}
+	l.visiting[key] = true
+	defer delete(l.visiting, key)
+
+	payload, err := json.Marshal(map[string]interface{}{"data": object, "included": l.included})
+	if err != nil {
+		return value, err
+	}
+	if err := jsonapi.UnmarshalPayload(bytes.NewReader(payload), value.Interface()); err != nil {
+		return value, decodeError(err)
+	}
+	return value, l.link(object, value)
+}
+
+// localNodes returns the local ids of the primary data of `v` and the ones set with LocalID, keyed by node key
+func localNodes(r *http.Request, v interface{}, doc *document) map[string]string {
+	lids := map[string]string{}
+	for k, lid := range getLocalIDs(r.Context()) {
+		lids[k] = lid
+	}
+
+	primary := doc.primary()
+	rv := reflect.ValueOf(v)
+	var models []interface{}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if !rv.IsNil() {
+			models = append(models, v)
+		}
+	case reflect.Slice:
+		for i := 0; i < rv.Len(); i++ {
+			models = append(models, rv.Index(i).Interface())
+		}
+	}
+	for i, model := range models {
+		if l, ok := model.(LocalIdentifiable); ok && i < len(primary) && l.JSONAPILID() != "" {
+			lids[nodeKey(primary[i])] = l.JSONAPILID()
+		}
+	}
+	if len(lids) == 0 {
+		return nil
+	}
+	return lids
+}
+
+// localNode is a resource object rendered with its local id
+type localNode struct {
+	*jsonapi.Node
+	LID string `json:"lid,omitempty"`
+}
+
+// withLocalID returns `node` with the local id of `lids` keyed by its node key, if any
+func withLocalID(node *jsonapi.Node, lids map[string]string) interface{} {
+	if node == nil {
+		return node
+	}
+	if lid, ok := lids[nodeKey(node)]; ok {
+		return &localNode{Node: node, LID: lid}
+	}
+	return node
+}
@@ -0,0 +1,173 @@
+package render_test
+
+import (
+	"context"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Note struct {
+	ID      int     `jsonapi:"primary,notes"`
+	Title   string  `jsonapi:"attr,title"`
+	Parent  *Note   `jsonapi:"relation,parent,omitempty"`
+	Related []*Note `jsonapi:"relation,related,omitempty"`
+
+	lid string
+}
+
+func (n *Note) JSONAPILID() string {
+	return n.lid
+}
+
+func (n *Note) SetJSONAPILID(lid string) {
+	n.lid = lid
+}
+
+func TestDecodeJSONAPI_LocalIDs(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedNote   *Note
+		expectedParent int
+		expectedError  *render.Error
+	}{
+		{
+			name:         "local id of the resource",
+			body:         `{"data":{"type":"notes","lid":"a","attributes":{"title":"new"}}}`,
+			expectedNote: &Note{Title: "new", lid: "a"},
+		},
+		{
+			name:           "reference to a resource of the document with an id",
+			body:           `{"data":{"type":"notes","id":"5","lid":"a","relationships":{"parent":{"data":{"type":"notes","lid":"a"}}}}}`,
+			expectedNote:   &Note{ID: 5, lid: "a"},
+			expectedParent: 5,
+		},
+		{
+			name:         "reference to a resource of the document without an id",
+			body:         `{"data":{"type":"notes","lid":"a","relationships":{"parent":{"data":{"type":"notes","lid":"a"}}}}}`,
+			expectedNote: &Note{lid: "a"},
+		},
+		{
+			name:          "unknown local id",
+			body:          `{"data":{"type":"notes","lid":"a","relationships":{"related":{"data":[{"type":"notes","id":"2"},{"type":"notes","lid":"b"}]}}}}`,
+			expectedError: &render.Error{Status: http.StatusBadRequest, Code: "invalid_local_id", Detail: `unknown local id "b" of type "notes"`, Source: &render.ErrorSource{Pointer: "/data/relationships/related/data/1/lid"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var note Note
+			err := render.DecodeJSONAPI(strings.NewReader(test.body), &note)
+			if test.expectedError != nil {
+				assert.Equal(t, test.expectedError, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				parent := note.Parent
+				note.Parent = nil
+				assert.Equal(t, test.expectedNote, &note)
+				if test.expectedParent != 0 && assert.NotNil(t, parent) {
+					assert.Equal(t, test.expectedParent, parent.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeJSONAPI_IncludedLocalIDs(t *testing.T) {
+
+	t.Run("should resolve local ids across included resources", func(t *testing.T) {
+		body := `{"data":{"type":"notes","lid":"a","relationships":{"parent":{"data":{"type":"notes","lid":"b"}}}},"included":[{"type":"notes","id":"2","lid":"b","relationships":{"parent":{"data":{"type":"notes","lid":"c"}}}},{"type":"notes","id":"3","lid":"c","attributes":{"title":"root"}}]}`
+		var note Note
+		if assert.NoError(t, render.DecodeJSONAPI(strings.NewReader(body), &note)) && assert.NotNil(t, note.Parent) {
+			assert.Equal(t, "a", note.JSONAPILID())
+			assert.Equal(t, 2, note.Parent.ID)
+			if assert.NotNil(t, note.Parent.Parent) {
+				assert.Equal(t, 3, note.Parent.Parent.ID)
+				assert.Equal(t, "root", note.Parent.Parent.Title)
+			}
+		}
+	})
+
+	t.Run("should decode the included resources referenced by local id only", func(t *testing.T) {
+		body := `{"data":{"type":"notes","lid":"a","relationships":{"related":{"data":[{"type":"notes","lid":"b"},{"type":"notes","lid":"c"}]}}},"included":[{"type":"notes","lid":"b","attributes":{"title":"B"},"relationships":{"parent":{"data":{"type":"notes","lid":"c"}}}},{"type":"notes","lid":"c","attributes":{"title":"C"}}]}`
+		var note Note
+		if assert.NoError(t, render.DecodeJSONAPI(strings.NewReader(body), &note)) && assert.Len(t, note.Related, 2) {
+			assert.Equal(t, "B", note.Related[0].Title)
+			assert.Equal(t, "b", note.Related[0].JSONAPILID())
+			assert.Equal(t, "C", note.Related[1].Title)
+			assert.Equal(t, "c", note.Related[1].JSONAPILID())
+			if assert.NotNil(t, note.Related[0].Parent) {
+				assert.Equal(t, "C", note.Related[0].Parent.Title)
+				assert.Equal(t, "c", note.Related[0].Parent.JSONAPILID())
+			}
+		}
+	})
+
+	t.Run("should set the local id of related resources", func(t *testing.T) {
+		body := `{"data":{"type":"notes","lid":"a","relationships":{"parent":{"data":{"type":"notes","lid":"b"}}}},"included":[{"type":"notes","id":"2","lid":"b"}]}`
+		var note Note
+		if assert.NoError(t, render.DecodeJSONAPI(strings.NewReader(body), &note)) && assert.NotNil(t, note.Parent) {
+			assert.Equal(t, 2, note.Parent.ID)
+			assert.Equal(t, "b", note.Parent.JSONAPILID())
+		}
+	})
+
+	t.Run("should point to unknown local ids of included resources", func(t *testing.T) {
+		body := `{"data":{"type":"notes","lid":"a"},"included":[{"type":"notes","id":"2","lid":"b","relationships":{"parent":{"data":{"type":"notes","lid":"c"}}}}]}`
+		var note Note
+		err := render.DecodeJSONAPI(strings.NewReader(body), &note)
+		assert.Equal(t, &render.Error{Status: http.StatusBadRequest, Code: "invalid_local_id", Detail: `unknown local id "c" of type "notes"`, Source: &render.ErrorSource{Pointer: "/included/0/relationships/parent/data/lid"}}, err)
+	})
+}
+
+func TestJSONAPI_LocalIDs(t *testing.T) {
+
+	t.Run("should render the local id of LocalIdentifiable resources", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/notes", nil)
+		w := httptest.NewRecorder()
+		render.JSONAPI(w, r, &Note{ID: 1, Title: "new", lid: "a"})
+		assert.Equal(t, `{"data":{"type":"notes","id":"1","attributes":{"title":"new"},"lid":"a"}}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should render the local ids set with LocalID", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/notes", nil)
+		w := httptest.NewRecorder()
+		render.LocalID(r, "notes", "b", "2")
+		render.JSONAPI(w, r, []*Note{{ID: 1}, {ID: 2}})
+		assert.Equal(t, `{"data":[{"type":"notes","id":"1","attributes":{"title":""}},{"type":"notes","id":"2","attributes":{"title":""},"lid":"b"}]}`, strings.TrimSpace(w.Body.String()))
+	})
+}
+
+func TestAtomicProcessor_LocalIDs(t *testing.T) {
+	var parents []*Note
+	p := &render.AtomicProcessor{}
+	p.Handle("notes", func(ctx context.Context, op *render.Operation) (*render.OperationResult, error) {
+		note := &Note{}
+		if err := op.Decode(note); err != nil {
+			return nil, err
+		}
+		note.ID = len(parents) + 10
+		parents = append(parents, note.Parent)
+		return &render.OperationResult{Data: note}, nil
+	})
+
+	body := `{"atomic:operations":[` +
+		`{"op":"add","data":{"type":"notes","lid":"a"}},` +
+		`{"op":"add","data":{"type":"notes","lid":"b","relationships":{"parent":{"data":{"type":"notes","lid":"a"}}}}},` +
+		`{"op":"add","data":{"type":"notes","relationships":{"parent":{"data":{"type":"notes","lid":"c"}}}}}]}`
+	r := httptest.NewRequest(http.MethodPost, "/operations", strings.NewReader(body))
+	r.Header.Set("Content-Type", atomicMediaType)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"unknown local id \"c\" of type \"notes\"","status":"400","code":"invalid_local_id","source":{"pointer":"/atomic:operations/2/data/relationships/parent/data/lid"}}]}`, strings.TrimSpace(w.Body.String()))
+	if assert.Len(t, parents, 2) && assert.NotNil(t, parents[1]) {
+		assert.Equal(t, 10, parents[1].ID)
+	}
+}
@@ -0,0 +1,152 @@
+package render
+
+import (
+	"context"
+	"errors"
+	chi_render "github.com/go-chi/render"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedMediaType is responded when the request Content-Type is the JSON:API media type
+	// with parameters other than `ext` and `profile`, or with an unsupported extension
+	ErrUnsupportedMediaType = errors.New("JSON:API media type must not have parameters other than ext and profile, nor unsupported extensions")
+	// ErrNotAcceptable is responded when every JSON:API media type in the request Accept header
+	// has parameters other than `ext` and `profile`, or an unsupported extension
+	ErrNotAcceptable = errors.New("at least one JSON:API media type in Accept must not have parameters other than ext and profile, nor unsupported extensions")
+)
+
+var (
+	// Extensions holds the URIs of the JSON:API extensions supported by the server
+	Extensions []string
+	// Profiles holds the URIs of the JSON:API profiles supported by the server
+	Profiles []string
+)
+
+// MediaTypeParamsCtxKey is the context key holding the MediaTypeParams negotiated by Negotiate
+var MediaTypeParamsCtxKey = &contextKey{"MediaTypeParams"}
+
+// MediaTypeParams holds the JSON:API extensions and profiles (as URIs) of a media type
+// see https://jsonapi.org/format/#media-type-parameter-rules
+type MediaTypeParams struct {
+	Ext     []string
+	Profile []string
+}
+
+// MediaType returns the JSON:API media type with `ext` and `profile` parameters
+func (p MediaTypeParams) MediaType() string {
+	params := map[string]string{}
+	if len(p.Ext) > 0 {
+		params["ext"] = strings.Join(p.Ext, " ")
+	}
+	if len(p.Profile) > 0 {
+		params["profile"] = strings.Join(p.Profile, " ")
+	}
+	return mime.FormatMediaType("application/vnd.api+json", params)
+}
+
+// HasExt reports whether the extension `uri` is applied
+func (p MediaTypeParams) HasExt(uri string) bool {
+	return contains(p.Ext, uri)
+}
+
+// HasProfile reports whether the profile `uri` is applied
+func (p MediaTypeParams) HasProfile(uri string) bool {
+	return contains(p.Profile, uri)
+}
+
+// GetMediaTypeParams returns the extensions and profiles negotiated by Negotiate for the request
+func GetMediaTypeParams(r *http.Request) MediaTypeParams {
+	params, _ := r.Context().Value(MediaTypeParamsCtxKey).(MediaTypeParams)
+	return params
+}
+
+// Negotiate is a middleware enforcing JSON:API content negotiation
+// see https://jsonapi.org/format/#content-negotiation-servers
+//
+// It responds with a JSON:API 415 Unsupported Media Type error if the request Content-Type is JSON:API with
+// malformed or unsupported media type parameters or extensions, and with a JSON:API 406 Not Acceptable error if every JSON:API
+// media type in the Accept header has unsupported media type parameters or extensions.
+// The applied extensions and profiles are stored in the request context, see GetMediaTypeParams
+func Negotiate(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		var negotiated MediaTypeParams
+
+		if GetRequestContentType(r) == ContentTypeJSONAPI {
+			_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			var ok bool
+			if err == nil {
+				negotiated, ok = parseMediaTypeParams(params)
+			}
+			if !ok {
+				chi_render.Status(r, http.StatusUnsupportedMediaType)
+				JSONAPI(w, r, ErrUnsupportedMediaType)
+				return
+			}
+		}
+
+		var ranges []mediaRange
+		for _, m := range parseAccept(r.Header.Get("Accept")) {
+			if GetContentType(m.mediaType) == ContentTypeJSONAPI {
+				ranges = append(ranges, m)
+			}
+		}
+		if len(ranges) > 0 {
+			sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+			acceptable := false
+			for _, m := range ranges {
+				if params, ok := parseMediaTypeParams(m.params); ok {
+					negotiated, acceptable = params, true
+					break
+				}
+			}
+			if !acceptable {
+				chi_render.Status(r, http.StatusNotAcceptable)
+				JSONAPI(w, r, ErrNotAcceptable)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), MediaTypeParamsCtxKey, negotiated)))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// parseMediaTypeParams returns the supported extensions and profiles of JSON:API media type parameters,
+// it reports false when there are parameters other than `ext` and `profile` or unsupported extensions
+func parseMediaTypeParams(params map[string]string) (MediaTypeParams, bool) {
+	var p MediaTypeParams
+	for name, value := range params {
+		switch name {
+		case "ext":
+			for _, uri := range strings.Fields(value) {
+				if !contains(Extensions, uri) {
+					return MediaTypeParams{}, false
+				}
+				p.Ext = append(p.Ext, uri)
+			}
+		case "profile":
+			// unsupported profiles are ignored
+			for _, uri := range strings.Fields(value) {
+				if contains(Profiles, uri) {
+					p.Profile = append(p.Profile, uri)
+				}
+			}
+		default:
+			return MediaTypeParams{}, false
+		}
+	}
+	return p, true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package render_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	atomicExt      = "https://jsonapi.org/ext/atomic"
+	versionExt     = "https://example.com/ext/version"
+	timestampsProf = "https://example.com/profiles/timestamps"
+)
+
+func TestNegotiate(t *testing.T) {
+	defer func(extensions, profiles []string) {
+		render.Extensions, render.Profiles = extensions, profiles
+	}(render.Extensions, render.Profiles)
+	render.Extensions = []string{atomicExt}
+	render.Profiles = []string{timestampsProf}
+
+	tests := []struct {
+		name              string
+		contentTypeHeader string
+		acceptHeader      string
+		status            int
+		expectedBody      []byte
+		expectedParams    render.MediaTypeParams
+	}{
+		{
+			name:              "json api without parameters",
+			contentTypeHeader: "application/vnd.api+json",
+			acceptHeader:      "application/vnd.api+json",
+			status:            http.StatusOK,
+		},
+		{
+			name:              "json api with ext and profile parameters",
+			contentTypeHeader: `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`,
+			acceptHeader:      `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"; profile="https://example.com/profiles/timestamps https://example.com/profiles/unknown"`,
+			status:            http.StatusOK,
+			expectedParams:    render.MediaTypeParams{Ext: []string{atomicExt}, Profile: []string{timestampsProf}},
+		},
+		{
+			name:              "falls back to request content type parameters",
+			contentTypeHeader: `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`,
+			acceptHeader:      "*/*",
+			status:            http.StatusOK,
+			expectedParams:    render.MediaTypeParams{Ext: []string{atomicExt}},
+		},
+		{
+			name:              "content type with unsupported parameter",
+			contentTypeHeader: "application/vnd.api+json; charset=utf-8",
+			acceptHeader:      "application/vnd.api+json",
+			status:            http.StatusUnsupportedMediaType,
+			expectedBody:      []byte(`{"errors":[{"title":"Unsupported Media Type","detail":"JSON:API media type must not have parameters other than ext and profile, nor unsupported extensions","status":"415"}]}`),
+		},
+		{
+			name:              "content type with malformed parameters",
+			contentTypeHeader: "application/vnd.api+json; charset",
+			acceptHeader:      "application/vnd.api+json",
+			status:            http.StatusUnsupportedMediaType,
+			expectedBody:      []byte(`{"errors":[{"title":"Unsupported Media Type","detail":"JSON:API media type must not have parameters other than ext and profile, nor unsupported extensions","status":"415"}]}`),
+		},
+		{
+			name:              "content type with unsupported extension",
+			contentTypeHeader: `application/vnd.api+json; ext="https://example.com/ext/version"`,
+			acceptHeader:      "application/vnd.api+json",
+			status:            http.StatusUnsupportedMediaType,
+			expectedBody:      []byte(`{"errors":[{"title":"Unsupported Media Type","detail":"JSON:API media type must not have parameters other than ext and profile, nor unsupported extensions","status":"415"}]}`),
+		},
+		{
+			name:              "every accepted json api media type with unsupported parameters",
+			contentTypeHeader: "application/vnd.api+json",
+			acceptHeader:      `application/vnd.api+json; charset=utf-8, application/vnd.api+json; ext="https://example.com/ext/version"`,
+			status:            http.StatusNotAcceptable,
+			expectedBody:      []byte(`{"errors":[{"title":"Not Acceptable","detail":"at least one JSON:API media type in Accept must not have parameters other than ext and profile, nor unsupported extensions","status":"406"}]}`),
+		},
+		{
+			name:              "at least one accepted json api media type without unsupported parameters",
+			contentTypeHeader: "application/vnd.api+json",
+			acceptHeader:      `application/vnd.api+json; charset=utf-8, application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"; q=0.5`,
+			status:            http.StatusOK,
+			expectedParams:    render.MediaTypeParams{Ext: []string{atomicExt}},
+		},
+		{
+			name:              "other content types are not affected",
+			contentTypeHeader: "application/json; charset=utf-8",
+			acceptHeader:      "application/json; charset=utf-8",
+			status:            http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var params render.MediaTypeParams
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				params = render.GetMediaTypeParams(r)
+				w.WriteHeader(http.StatusOK)
+			})
+			r := httptest.NewRequest(http.MethodPost, "http://www.example.com", nil)
+			r.Header.Set("Content-Type", test.contentTypeHeader)
+			r.Header.Set("Accept", test.acceptHeader)
+			w := httptest.NewRecorder()
+			render.Negotiate(next).ServeHTTP(w, r)
+			assert.Equal(t, test.status, w.Code)
+			assert.Equal(t, string(test.expectedBody), strings.TrimSpace(w.Body.String()))
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestNegotiate_ResponseContentType(t *testing.T) {
+	defer func(extensions, profiles []string) {
+		render.Extensions, render.Profiles = extensions, profiles
+	}(render.Extensions, render.Profiles)
+	render.Extensions = []string{atomicExt, versionExt}
+	render.Profiles = []string{timestampsProf}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		render.DefaultResponder(w, r, &Blog{ID: 1})
+	})
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	r.Header.Set("Accept", `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic https://example.com/ext/version"; profile="https://example.com/profiles/timestamps"`)
+	w := httptest.NewRecorder()
+	render.Negotiate(next).ServeHTTP(w, r)
+	assert.Equal(t, `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic https://example.com/ext/version"; profile="https://example.com/profiles/timestamps"`, w.Header().Get("Content-Type"))
+	assert.Equal(t, []string{"Accept"}, w.Header()["Vary"])
+}
@@ -0,0 +1,28 @@
+package render
+
+import (
+	"context"
+	"github.com/google/jsonapi"
+	"net/http"
+	"net/url"
+)
+
+// PaginationCtxKey is the context key holding the Paginator set with Pagination
+var PaginationCtxKey = &contextKey{"Pagination"}
+
+// Paginator provides the pagination links of collection payloads
+type Paginator interface {
+	// PaginationLinks returns the `first`, `prev`, `next` and `last` links built from the request URL `u`,
+	// `count` is the number of resources in the rendered page
+	PaginationLinks(u *url.URL, count int) jsonapi.Links
+}
+
+// Pagination sets the Paginator whose links JSONAPI adds to collection payloads, see queryparams.Paginate
+func Pagination(r *http.Request, p Paginator) {
+	*r = *r.WithContext(context.WithValue(r.Context(), PaginationCtxKey, p))
+}
+
+func getPaginator(ctx context.Context) Paginator {
+	p, _ := ctx.Value(PaginationCtxKey).(Paginator)
+	return p
+}
@@ -0,0 +1,65 @@
+package render_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/google/jsonapi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type paginator struct{}
+
+func (paginator) PaginationLinks(u *url.URL, count int) jsonapi.Links {
+	if count == 0 {
+		return jsonapi.Links{jsonapi.KeyFirstPage: u.Path}
+	}
+	return jsonapi.Links{jsonapi.KeyFirstPage: u.Path, jsonapi.KeyNextPage: u.Path + "?next"}
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      interface{}
+		links        jsonapi.Links
+		expectedBody string
+	}{
+		{
+			name:         "collection",
+			payload:      []*Post{{ID: 1}},
+			expectedBody: `{"data":[{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":""},"relationships":{"comments":{"data":[]}}}],"links":{"first":"/posts","next":"/posts?next"}}`,
+		},
+		{
+			name:         "empty collection",
+			payload:      []*Post{},
+			expectedBody: `{"data":[],"links":{"first":"/posts"}}`,
+		},
+		{
+			name:         "links set with Links take precedence",
+			payload:      []*Post{{ID: 1}},
+			links:        jsonapi.Links{jsonapi.KeyNextPage: "/posts?other"},
+			expectedBody: `{"data":[{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":""},"relationships":{"comments":{"data":[]}}}],"links":{"first":"/posts","next":"/posts?other"}}`,
+		},
+		{
+			name:         "single resource",
+			payload:      &Post{ID: 1},
+			expectedBody: `{"data":{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":""},"relationships":{"comments":{"data":[]}}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			w := httptest.NewRecorder()
+			render.Pagination(r, paginator{})
+			if test.links != nil {
+				render.Links(r, test.links)
+			}
+			render.JSONAPI(w, r, test.payload)
+			assert.Equal(t, test.expectedBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"net/http"
+	"reflect"
+)
+
+// PresentFieldsCtxKey is the context key holding the PresentFields of the resource decoded by DefaultDecoder
+var PresentFieldsCtxKey = &contextKey{"PresentFields"}
+
+// PresentFields holds the names of the attributes and relationships present in a decoded resource object,
+// telling apart omitted members from members set to their zero value
+type PresentFields struct {
+	Attributes    map[string]bool
+	Relationships map[string]bool
+	// NullAttributes holds the names of the present attributes set to null
+	NullAttributes map[string]bool
+}
+
+// HasAttribute reports whether the attribute `name` is present
+func (p *PresentFields) HasAttribute(name string) bool {
+	return p != nil && p.Attributes[name]
+}
+
+// IsNullAttribute reports whether the attribute `name` is present and set to null
+func (p *PresentFields) IsNullAttribute(name string) bool {
+	return p != nil && p.NullAttributes[name]
+}
+
+// HasRelationship reports whether the relationship `name` is present
+func (p *PresentFields) HasRelationship(name string) bool {
+	return p != nil && p.Relationships[name]
+}
+
+// GetPresentFields returns the attributes and relationships present in the resource decoded by DefaultDecoder,
+// or nil if none was decoded
+func GetPresentFields(r *http.Request) *PresentFields {
+	present, _ := r.Context().Value(PresentFieldsCtxKey).(*PresentFields)
+	return present
+}
+
+// ApplyPresent copies the attributes and relationships of `src` that are in `present` onto `dst`,
+// e.g. a PATCH request decoded into `src` onto the resource `dst` loaded from storage.
+// `src` and `dst` must be pointers to the same struct
+func ApplyPresent(dst, src interface{}, present *PresentFields) error {
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || sv.Kind() != reflect.Ptr || sv.IsNil() || dv.Type() != sv.Type() {
+		return fmt.Errorf("cannot apply %T onto %T, expected pointers to the same struct", src, dst)
+	}
+	res := resource.Of(dv.Type())
+	if res == nil {
+		return fmt.Errorf("cannot apply %T onto %T, expected pointers to the same struct", src, dst)
+	}
+	dv, sv = dv.Elem(), sv.Elem()
+	for _, attr := range res.Attributes {
+		if present.HasAttribute(attr.Name) {
+			dv.Field(attr.Index).Set(sv.Field(attr.Index))
+		}
+	}
+	for _, rel := range res.Relationships {
+		if present.HasRelationship(rel.Name) {
+			dv.Field(rel.Index).Set(sv.Field(rel.Index))
+		}
+	}
+	return nil
+}
+
+// presentFields returns the attributes and relationships present in the primary data of the document `body`
+func presentFields(body []byte) *PresentFields {
+	present := &PresentFields{Attributes: map[string]bool{}, Relationships: map[string]bool{}, NullAttributes: map[string]bool{}}
+	var doc struct {
+		Data struct {
+			Attributes    map[string]json.RawMessage `json:"attributes"`
+			Relationships map[string]json.RawMessage `json:"relationships"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return present
+	}
+	for name, value := range doc.Data.Attributes {
+		present.Attributes[name] = true
+		if bytes.Equal(value, []byte("null")) {
+			present.NullAttributes[name] = true
+		}
+	}
+	for name := range doc.Data.Relationships {
+		present.Relationships[name] = true
+	}
+	return present
+}
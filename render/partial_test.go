@@ -0,0 +1,104 @@
+package render_test
+
+import (
+	"bytes"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodePartial(t *testing.T) {
+
+	t.Run("should return the attributes and relationships present in the document", func(t *testing.T) {
+		body := `{"data":{"type":"blogs","id":"1","attributes":{"title":"","view_count":3},"relationships":{"current_post":{"data":null}}}}`
+		var v Blog
+		present, err := render.DecodePartial(bytes.NewBufferString(body), &v)
+		assert.NoError(t, err)
+		assert.True(t, present.HasAttribute("title"))
+		assert.True(t, present.HasAttribute("view_count"))
+		assert.False(t, present.HasAttribute("current_post_id"))
+		assert.True(t, present.HasRelationship("current_post"))
+		assert.False(t, present.HasRelationship("posts"))
+	})
+
+	t.Run("should tell apart the attributes set to null", func(t *testing.T) {
+		body := `{"data":{"type":"blogs","id":"1","attributes":{"title":null,"view_count":0}}}`
+		var v Blog
+		present, err := render.DecodePartial(bytes.NewBufferString(body), &v)
+		assert.NoError(t, err)
+		assert.True(t, present.HasAttribute("title"))
+		assert.True(t, present.IsNullAttribute("title"))
+		assert.False(t, present.IsNullAttribute("view_count"))
+		assert.False(t, present.IsNullAttribute("current_post_id"))
+	})
+
+	t.Run("should return no present fields on error", func(t *testing.T) {
+		var v Blog
+		present, err := render.DecodePartial(bytes.NewBufferString(`{"data":`), &v)
+		assert.Error(t, err)
+		assert.Nil(t, present)
+	})
+}
+
+func TestGetPresentFields(t *testing.T) {
+
+	t.Run("should hold the present fields of the decoded request", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPatch, "/blogs/1", bytes.NewBufferString(`{"data":{"type":"blogs","id":"1","attributes":{"title":"New"}}}`))
+		r.Header.Set("Content-Type", "application/vnd.api+json")
+		assert.Nil(t, render.GetPresentFields(r))
+
+		var v Blog
+		assert.NoError(t, render.DefaultDecoder(r, &v))
+		present := render.GetPresentFields(r)
+		assert.Equal(t, map[string]bool{"title": true}, present.Attributes)
+		assert.Empty(t, present.Relationships)
+	})
+}
+
+func TestApplyPresent(t *testing.T) {
+	post := &Post{ID: 2}
+	tests := []struct {
+		name     string
+		present  *render.PresentFields
+		expected Blog
+	}{
+		{
+			name:     "nothing present",
+			present:  &render.PresentFields{},
+			expected: Blog{ID: 1, Title: "Old", ViewCount: 10, CurrentPost: post},
+		},
+		{
+			name:     "zero values",
+			present:  &render.PresentFields{Attributes: map[string]bool{"view_count": true}, Relationships: map[string]bool{"current_post": true}},
+			expected: Blog{ID: 1, Title: "Old"},
+		},
+		{
+			name:     "attributes",
+			present:  &render.PresentFields{Attributes: map[string]bool{"title": true}},
+			expected: Blog{ID: 1, Title: "New", ViewCount: 10, CurrentPost: post},
+		},
+		{
+			name:     "nil",
+			expected: Blog{ID: 1, Title: "Old", ViewCount: 10, CurrentPost: post},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := &Blog{ID: 1, Title: "Old", ViewCount: 10, CurrentPost: post}
+			src := &Blog{ID: 1, Title: "New"}
+			assert.NoError(t, render.ApplyPresent(dst, src, test.present))
+			assert.Equal(t, test.expected, *dst)
+		})
+	}
+}
+
+func TestApplyPresentInvalid(t *testing.T) {
+
+	t.Run("should reject values that are not pointers to structs of the same type", func(t *testing.T) {
+		assert.Error(t, render.ApplyPresent(&Blog{}, &Post{}, &render.PresentFields{}))
+		assert.Error(t, render.ApplyPresent(Blog{}, Blog{}, &render.PresentFields{}))
+		assert.Error(t, render.ApplyPresent(new(int), new(int), &render.PresentFields{}))
+	})
+}
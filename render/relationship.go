@@ -0,0 +1,289 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	chi_render "github.com/go-chi/render"
+	"github.com/google/jsonapi"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// ResourceIdentifier is a resource identifier object, see https://jsonapi.org/format/#document-resource-identifier-objects
+type ResourceIdentifier struct {
+	Type string                 `json:"type"`
+	ID   string                 `json:"id"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// JSONAPIRelationship renders the relationship `name` of `v`, a struct pointer, as a document holding only
+// resource identifiers, as returned by relationship endpoints such as `/blogs/1/relationships/posts`.
+// Links and meta of RelationshipLinkable and RelationshipMetable resources are rendered as top-level members
+func JSONAPIRelationship(w http.ResponseWriter, r *http.Request, v interface{}, name string) {
+
+	w.Header().Set("Content-Type", GetMediaTypeParams(r).MediaType())
+	addVary(w, "Accept")
+
+	buf := &bytes.Buffer{}
+	doc, err := newRelationshipDocument(v, name)
+	if err == nil {
+		doc.setTopLevel(r, false, 0)
+		err = doc.marshal(buf)
+	}
+	if err != nil {
+		errs := flattenErrors(err)
+		writeErrors(w, r, errorsStatus(errs), false, errs...)
+		return
+	}
+
+	if status, ok := r.Context().Value(chi_render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// DecodeRelationship decodes a document holding the resource identifiers of the relationship `name`
+// of `v`, a struct pointer, and replaces the relationship with them (e.g. `PATCH /blogs/1/relationships/posts`).
+// Related resources are set as struct pointers with only their primary field
+func DecodeRelationship(r io.Reader, v interface{}, name string) error {
+	return decodeRelationship(r, v, name, replaceRelationship, false)
+}
+
+// AddRelationship decodes a document holding the resource identifiers of the to-many relationship `name`
+// of `v`, a struct pointer, and adds the ones not already in the relationship (e.g. `POST /blogs/1/relationships/posts`)
+func AddRelationship(r io.Reader, v interface{}, name string) error {
+	return decodeRelationship(r, v, name, addRelationship, false)
+}
+
+// RemoveRelationship decodes a document holding the resource identifiers of the to-many relationship `name`
+// of `v`, a struct pointer, and removes them from the relationship (e.g. `DELETE /blogs/1/relationships/posts`)
+func RemoveRelationship(r io.Reader, v interface{}, name string) error {
+	return decodeRelationship(r, v, name, removeRelationship, false)
+}
+
+// BindRelationship updates the relationship `name` of `v` from the request body as per the request method:
+// PATCH replaces it (see DecodeRelationship), POST adds to it (see AddRelationship) and DELETE removes
+// from it (see RemoveRelationship). Other methods are responded with 405 Method Not Allowed.
+// Unknown members of the documents of routes using the Strict middleware are reported as DecodeError
+func BindRelationship(r *http.Request, v interface{}, name string) error {
+	var update relationshipUpdate
+	switch r.Method {
+	case http.MethodPatch:
+		update = replaceRelationship
+	case http.MethodPost:
+		update = addRelationship
+	case http.MethodDelete:
+		update = removeRelationship
+	default:
+		return &Error{Status: http.StatusMethodNotAllowed, Detail: fmt.Sprintf("method %s is not allowed on relationships", r.Method)}
+	}
+	return decodeRelationship(r.Body, v, name, update, isStrict(r.Context()))
+}
+
+// newRelationshipDocument returns the document of the relationship `name` of `v`
+func newRelationshipDocument(v interface{}, name string) (*document, error) {
+	res := resource.Of(reflect.TypeOf(v))
+	if res == nil || reflect.TypeOf(v).Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("cannot render relationship of %T, expected a struct pointer", v)
+	}
+	rel := res.Relationship(name)
+	if rel == nil {
+		return nil, fmt.Errorf("unknown relationship %q of %T", name, v)
+	}
+
+	payload, err := jsonapi.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	node := payload.(*jsonapi.OnePayload).Data
+
+	doc := &document{}
+	switch r := node.Relationships[name].(type) {
+	case *jsonapi.RelationshipOneNode:
+		doc.Data, doc.Links, doc.Meta = r.Data, r.Links, r.Meta
+	case *jsonapi.RelationshipManyNode:
+		doc.Data, doc.Links, doc.Meta = r.Data, r.Links, r.Meta
+	default:
+		if rel.Many {
+			doc.Data = []*jsonapi.Node{}
+		} else {
+			doc.Data = (*jsonapi.Node)(nil)
+		}
+	}
+	return doc, nil
+}
+
+type relationshipUpdate func(field reflect.Value, values []reflect.Value, related *resource.Resource) error
+
+// decodeRelationship decodes the resource identifiers of the relationship `name` of `v` and applies `update`,
+// unknown members are reported when `strict`
+func decodeRelationship(r io.Reader, v interface{}, name string, update relationshipUpdate, strict bool) error {
+	defer io.Copy(ioutil.Discard, r)
+
+	rv := reflect.ValueOf(v)
+	res := resource.Of(reflect.TypeOf(v))
+	if res == nil || rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot decode relationship into %T, expected a struct pointer", v)
+	}
+	rel := res.Relationship(name)
+	if rel == nil {
+		return fmt.Errorf("unknown relationship %q of %T", name, v)
+	}
+	related := rel.Related()
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return newRelationshipError(http.StatusBadRequest, "", "request body is not a valid JSON:API document")
+	}
+	if doc.Data == nil {
+		return newRelationshipError(http.StatusBadRequest, "", "missing `data` member")
+	}
+	if strict {
+		if errs := checkUnknownRelationshipMembers(body); len(errs) > 0 {
+			return errs
+		}
+	}
+
+	var identifiers []*ResourceIdentifier
+	var pointers []string
+	if rel.Many {
+		if err := json.Unmarshal(doc.Data, &identifiers); err != nil || identifiers == nil {
+			return newRelationshipError(http.StatusBadRequest, "/data", "expected an array of resource identifiers for a to-many relationship")
+		}
+		for i := range identifiers {
+			pointers = append(pointers, "/data/"+strconv.Itoa(i))
+		}
+	} else {
+		var identifier *ResourceIdentifier
+		if err := json.Unmarshal(doc.Data, &identifier); err != nil {
+			return newRelationshipError(http.StatusBadRequest, "/data", "expected a resource identifier or null for a to-one relationship")
+		}
+		if identifier != nil {
+			identifiers, pointers = append(identifiers, identifier), append(pointers, "/data")
+		}
+	}
+
+	values := make([]reflect.Value, 0, len(identifiers))
+	for i, identifier := range identifiers {
+		switch {
+		case identifier == nil:
+			return newRelationshipError(http.StatusBadRequest, pointers[i], "expected a resource identifier")
+		case identifier.Type == "":
+			return newRelationshipError(http.StatusBadRequest, pointers[i]+"/type", "missing resource type")
+		case identifier.ID == "":
+			return newRelationshipError(http.StatusBadRequest, pointers[i]+"/id", "missing resource id")
+		case identifier.Type != related.Name:
+			return newRelationshipError(http.StatusConflict, pointers[i]+"/type", fmt.Sprintf("expected resource type %q, got %q", related.Name, identifier.Type))
+		}
+		value := reflect.New(related.Type)
+		if err := setPrimary(value.Elem().Field(related.Primary.Index), identifier.ID); err != nil {
+			return newRelationshipError(http.StatusBadRequest, pointers[i]+"/id", err.Error())
+		}
+		values = append(values, value)
+	}
+
+	return update(rv.Elem().Field(rel.Index), values, related)
+}
+
+func replaceRelationship(field reflect.Value, values []reflect.Value, related *resource.Resource) error {
+	if field.Kind() != reflect.Slice {
+		if len(values) == 0 {
+			field.Set(reflect.Zero(field.Type()))
+		} else {
+			field.Set(values[0])
+		}
+		return nil
+	}
+	field.Set(reflect.Append(reflect.MakeSlice(field.Type(), 0, len(values)), values...))
+	return nil
+}
+
+func addRelationship(field reflect.Value, values []reflect.Value, related *resource.Resource) error {
+	if field.Kind() != reflect.Slice {
+		return errToOneRelationship
+	}
+	ids := primaryIDs(related, field)
+	for _, value := range values {
+		if id := primaryID(related, value); !ids[id] {
+			ids[id] = true
+			field.Set(reflect.Append(field, value))
+		}
+	}
+	return nil
+}
+
+func removeRelationship(field reflect.Value, values []reflect.Value, related *resource.Resource) error {
+	if field.Kind() != reflect.Slice {
+		return errToOneRelationship
+	}
+	ids := primaryIDs(related, reflect.Append(reflect.MakeSlice(field.Type(), 0, len(values)), values...))
+	kept := reflect.MakeSlice(field.Type(), 0, field.Len())
+	for i := 0; i < field.Len(); i++ {
+		if !ids[primaryID(related, field.Index(i))] {
+			kept = reflect.Append(kept, field.Index(i))
+		}
+	}
+	field.Set(kept)
+	return nil
+}
+
+var errToOneRelationship = &Error{Status: http.StatusForbidden, Detail: "resources can only be added to or removed from to-many relationships"}
+
+// primaryIDs returns the set of the primary fields of the resources of `slice`
+func primaryIDs(res *resource.Resource, slice reflect.Value) map[string]bool {
+	ids := map[string]bool{}
+	for i := 0; i < slice.Len(); i++ {
+		ids[primaryID(res, slice.Index(i))] = true
+	}
+	return ids
+}
+
+// primaryID returns the primary field of `value`, a struct pointer, formatted as a resource id
+func primaryID(res *resource.Resource, value reflect.Value) string {
+	if value.IsNil() {
+		return ""
+	}
+	return fmt.Sprint(value.Elem().Field(res.Primary.Index).Interface())
+}
+
+// setPrimary sets the primary field `field` to `id`, converting it to an integer if needed
+func setPrimary(field reflect.Value, id string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(id)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(id, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid resource id %q, expected an integer", id)
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(id, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid resource id %q, expected a positive integer", id)
+		}
+		field.SetUint(u)
+	default:
+		return fmt.Errorf("unsupported primary field of type %s", field.Type())
+	}
+	return nil
+}
+
+func newRelationshipError(status int, pointer, detail string) error {
+	err := &Error{Status: status, Code: "invalid_relationship", Detail: detail}
+	if pointer != "" {
+		err.Source = &ErrorSource{Pointer: pointer}
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+package render_test
+
+import (
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/google/jsonapi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONAPIRelationship(t *testing.T) {
+	blog := &Blog{ID: 1, Posts: []*Post{{ID: 2}, {ID: 3}}, CurrentPost: &Post{ID: 3}}
+
+	tests := []struct {
+		name           string
+		v              interface{}
+		relationship   string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "to-many",
+			v:              blog,
+			relationship:   "posts",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data":[{"type":"posts","id":"2"},{"type":"posts","id":"3"}],"links":{"self":"/blogs/1/relationships/posts"}}`,
+		},
+		{
+			name:           "to-one",
+			v:              blog,
+			relationship:   "current_post",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data":{"type":"posts","id":"3"},"links":{"self":"/blogs/1/relationships/posts"}}`,
+		},
+		{
+			name:           "empty to-one",
+			v:              &Blog{ID: 1},
+			relationship:   "current_post",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data":null,"links":{"self":"/blogs/1/relationships/posts"}}`,
+		},
+		{
+			name:           "empty to-many",
+			v:              &Blog{ID: 1},
+			relationship:   "posts",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data":[],"links":{"self":"/blogs/1/relationships/posts"}}`,
+		},
+		{
+			name:           "unknown relationship",
+			v:              blog,
+			relationship:   "comments",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"errors":[{"title":"Internal Server Error","detail":"unknown relationship \"comments\" of *render_test.Blog","status":"500"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/blogs/1/relationships/posts", nil)
+			w := httptest.NewRecorder()
+			render.Links(r, jsonapi.Links{"self": "/blogs/1/relationships/posts"})
+			render.JSONAPIRelationship(w, r, test.v, test.relationship)
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, test.expectedBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+func TestBindRelationship(t *testing.T) {
+	postIDs := func(posts []*Post) (ids []int) {
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		return
+	}
+
+	tests := []struct {
+		name            string
+		method          string
+		relationship    string
+		body            string
+		expectedPostIDs []int
+		expectedCurrent *Post
+		expectedError   *render.Error
+	}{
+		{
+			name:            "replace to-many",
+			method:          http.MethodPatch,
+			relationship:    "posts",
+			body:            `{"data":[{"type":"posts","id":"4"},{"type":"posts","id":"5"}]}`,
+			expectedPostIDs: []int{4, 5},
+			expectedCurrent: &Post{ID: 3},
+		},
+		{
+			name:            "clear to-many",
+			method:          http.MethodPatch,
+			relationship:    "posts",
+			body:            `{"data":[]}`,
+			expectedCurrent: &Post{ID: 3},
+		},
+		{
+			name:            "add to to-many",
+			method:          http.MethodPost,
+			relationship:    "posts",
+			body:            `{"data":[{"type":"posts","id":"3"},{"type":"posts","id":"4"}]}`,
+			expectedPostIDs: []int{2, 3, 4},
+			expectedCurrent: &Post{ID: 3},
+		},
+		{
+			name:            "remove from to-many",
+			method:          http.MethodDelete,
+			relationship:    "posts",
+			body:            `{"data":[{"type":"posts","id":"2"},{"type":"posts","id":"9"}]}`,
+			expectedPostIDs: []int{3},
+			expectedCurrent: &Post{ID: 3},
+		},
+		{
+			name:            "replace to-one",
+			method:          http.MethodPatch,
+			relationship:    "current_post",
+			body:            `{"data":{"type":"posts","id":"2"}}`,
+			expectedPostIDs: []int{2, 3},
+			expectedCurrent: &Post{ID: 2},
+		},
+		{
+			name:            "clear to-one",
+			method:          http.MethodPatch,
+			relationship:    "current_post",
+			body:            `{"data":null}`,
+			expectedPostIDs: []int{2, 3},
+		},
+		{
+			name:          "add to to-one",
+			method:        http.MethodPost,
+			relationship:  "current_post",
+			body:          `{"data":{"type":"posts","id":"2"}}`,
+			expectedError: &render.Error{Status: http.StatusForbidden, Detail: "resources can only be added to or removed from to-many relationships"},
+		},
+		{
+			name:          "type mismatch",
+			method:        http.MethodPatch,
+			relationship:  "posts",
+			body:          `{"data":[{"type":"posts","id":"4"},{"type":"comments","id":"5"}]}`,
+			expectedError: &render.Error{Status: http.StatusConflict, Code: "invalid_relationship", Detail: `expected resource type "posts", got "comments"`, Source: &render.ErrorSource{Pointer: "/data/1/type"}},
+		},
+		{
+			name:          "invalid id",
+			method:        http.MethodPatch,
+			relationship:  "current_post",
+			body:          `{"data":{"type":"posts","id":"abc"}}`,
+			expectedError: &render.Error{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: `invalid resource id "abc", expected an integer`, Source: &render.ErrorSource{Pointer: "/data/id"}},
+		},
+		{
+			name:          "missing id",
+			method:        http.MethodPatch,
+			relationship:  "posts",
+			body:          `{"data":[{"type":"posts"}]}`,
+			expectedError: &render.Error{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: "missing resource id", Source: &render.ErrorSource{Pointer: "/data/0/id"}},
+		},
+		{
+			name:          "to-one given to to-many",
+			method:        http.MethodPatch,
+			relationship:  "posts",
+			body:          `{"data":{"type":"posts","id":"2"}}`,
+			expectedError: &render.Error{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: "expected an array of resource identifiers for a to-many relationship", Source: &render.ErrorSource{Pointer: "/data"}},
+		},
+		{
+			name:          "missing data",
+			method:        http.MethodPatch,
+			relationship:  "posts",
+			body:          `{}`,
+			expectedError: &render.Error{Status: http.StatusBadRequest, Code: "invalid_relationship", Detail: "missing `data` member"},
+		},
+		{
+			name:          "method not allowed",
+			method:        http.MethodPut,
+			relationship:  "posts",
+			body:          `{"data":[]}`,
+			expectedError: &render.Error{Status: http.StatusMethodNotAllowed, Detail: "method PUT is not allowed on relationships"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blog := &Blog{ID: 1, Posts: []*Post{{ID: 2}, {ID: 3}}, CurrentPost: &Post{ID: 3}}
+			r := httptest.NewRequest(test.method, "/blogs/1/relationships/"+test.relationship, strings.NewReader(test.body))
+			err := render.BindRelationship(r, blog, test.relationship)
+			if test.expectedError != nil {
+				assert.Equal(t, test.expectedError, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expectedPostIDs, postIDs(blog.Posts))
+				assert.Equal(t, test.expectedCurrent, blog.CurrentPost)
+			}
+		})
+	}
+}
@@ -1,3 +1,12 @@
 // Package render provides JSON API responder and decoder that integrates  with https://github.com/go-chi/render
 // it also provides a `DefaultResponder` and `DefaultDecoder` for easy integration
 package render
+
+// contextKey is a value for use with context.WithValue
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string {
+	return "jsonapi render context value " + k.name
+}
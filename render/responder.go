@@ -3,15 +3,16 @@ package render
 import (
 	"bytes"
 	chi_render "github.com/go-chi/render"
-	"github.com/google/jsonapi"
 	"net/http"
-	"strconv"
+	"strings"
 )
 
 // Respond handles JSON API responses and delegates any other content type to github.com/go-chi/render
 // automatically setting the Content-Type based on request headers
 func DefaultResponder(w http.ResponseWriter, r *http.Request, v interface{}) {
 
+	addVary(w, "Accept")
+
 	// Format response based on request Accept header.
 	switch GetAcceptedContentType(r) {
 	case ContentTypeJSONAPI:
@@ -22,46 +23,57 @@ func DefaultResponder(w http.ResponseWriter, r *http.Request, v interface{}) {
 }
 
 // JSONAPI marshals `v` to JSONAPI, automatically setting Content-Type as application/vnd.api+json
+// along with the `ext` and `profile` parameters negotiated by Negotiate.
+// Errors, including multi-errors and []error, are rendered as a JSON:API error document, Iterator values
+// are streamed (see Stream) and AtomicResults are rendered as an Atomic Operations results document
 func JSONAPI(w http.ResponseWriter, r *http.Request, v interface{}) {
 
-	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.Header().Set("Content-Type", GetMediaTypeParams(r).MediaType())
+	addVary(w, "Accept")
 
 	switch v.(type) {
 	case error:
 		renderError(w, r, v.(error))
+	case []error:
+		renderError(w, r, Errors(v.([]error)))
+	case Iterator:
+		Stream(w, r, v.(Iterator))
+	case AtomicResults:
+		renderResults(w, r, v.(AtomicResults))
 	default:
 		renderPayload(w, r, v)
 	}
 
 }
 
-func toJSONAPIErrors(status int, errors ...error) (jsonapierrors []*jsonapi.ErrorObject) {
-	for _, e := range errors {
-		jsonapierrors = append(jsonapierrors, &jsonapi.ErrorObject{
-			Title:  http.StatusText(status),
-			Detail: e.Error(),
-			Status: strconv.Itoa(status),
-			Code:   "",
-			Meta:   nil,
-		})
+// renderError renders `err` as a JSON:API error document, multi-errors (see Errors) render one error object per error
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	errs := flattenErrors(err)
+	status, explicit := r.Context().Value(chi_render.StatusCtxKey).(int)
+	if !explicit {
+		status = errorsStatus(errs)
 	}
-	return
+	writeErrors(w, r, status, explicit, errs...)
 }
 
-func renderError(w http.ResponseWriter, r *http.Request, err error) {
-	status := http.StatusInternalServerError // default if not set in context
-	if i, ok := r.Context().Value(chi_render.StatusCtxKey).(int); ok {
-		status = i
-	}
+// writeErrors writes the error document of `errs` with `status`, set explicitly with render.Status
+// when `explicit`, see toJSONAPIErrors and ErrorSanitizer
+func writeErrors(w http.ResponseWriter, r *http.Request, status int, explicit bool, errs ...error) {
+	objs := toJSONAPIErrors(status, explicit, errs...)
+	sanitizeErrors(r, objs, errs)
 	w.WriteHeader(status)
-	_ = jsonapi.MarshalErrors(w, toJSONAPIErrors(status, err))
+	_ = marshalErrors(w, objs)
 }
 
 func renderPayload(w http.ResponseWriter, r *http.Request, v interface{}) {
 	buf := &bytes.Buffer{}
-	if err := jsonapi.MarshalPayload(buf, v); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = jsonapi.MarshalErrors(w, toJSONAPIErrors(http.StatusInternalServerError, err))
+	doc, err := newDocument(r, v)
+	if err == nil {
+		err = doc.marshal(buf)
+	}
+	if err != nil {
+		errs := flattenErrors(err)
+		writeErrors(w, r, errorsStatus(errs), false, errs...)
 		return
 	}
 
@@ -71,3 +83,15 @@ func renderPayload(w http.ResponseWriter, r *http.Request, v interface{}) {
 	_, _ = w.Write(buf.Bytes())
 
 }
+
+// addVary adds `header` to the Vary header unless already present
+func addVary(w http.ResponseWriter, header string) {
+	for _, v := range w.Header()["Vary"] {
+		for _, h := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(h), header) {
+				return
+			}
+		}
+	}
+	w.Header().Add("Vary", header)
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-chi/chi/middleware"
+	"log"
+	"net/http"
+)
+
+// ErrorSanitizer, when set, is applied to every error object with a 5xx status before it is rendered,
+// along with the original error. Set it to SanitizeError to hide internal error details in production
+var ErrorSanitizer func(r *http.Request, obj *Error, err error) *Error
+
+// ErrorLogger logs the original error of the error objects sanitized by SanitizeError
+var ErrorLogger = func(r *http.Request, correlationID string, err error) {
+	log.Printf("[%s] %s %s: %v", correlationID, r.Method, r.URL.Path, err)
+}
+
+// InternalErrorDetail is the generic `detail` of the error objects sanitized by SanitizeError
+var InternalErrorDetail = "An internal error occurred"
+
+// SanitizeError is an ErrorSanitizer replacing `detail` with InternalErrorDetail and a correlation ID,
+// which is also set as the error object `id`. The correlation ID is the chi middleware.RequestID when present,
+// a random one otherwise. The original error is logged through ErrorLogger
+func SanitizeError(r *http.Request, obj *Error, err error) *Error {
+	correlationID := middleware.GetReqID(r.Context())
+	if correlationID == "" {
+		correlationID = newCorrelationID()
+	}
+	if ErrorLogger != nil {
+		ErrorLogger(r, correlationID, err)
+	}
+	return &Error{
+		ID:     correlationID,
+		Title:  obj.Title,
+		Detail: fmt.Sprintf("%s (correlation id: %s)", InternalErrorDetail, correlationID),
+		Status: obj.Status,
+		Code:   obj.Code,
+	}
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sanitizeErrors applies ErrorSanitizer to the 5xx error objects of `objs`, `errs` holds the original errors
+func sanitizeErrors(r *http.Request, objs []*Error, errs []error) {
+	if ErrorSanitizer == nil {
+		return
+	}
+	for i, obj := range objs {
+		if obj.Status >= 500 {
+			objs[i] = ErrorSanitizer(r, obj, errs[i])
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package render_test
+
+import (
+	"errors"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeError(t *testing.T) {
+	defer func(sanitizer func(*http.Request, *render.Error, error) *render.Error, logger func(*http.Request, string, error)) {
+		render.ErrorSanitizer, render.ErrorLogger = sanitizer, logger
+	}(render.ErrorSanitizer, render.ErrorLogger)
+
+	var logged []string
+	render.ErrorSanitizer = render.SanitizeError
+	render.ErrorLogger = func(r *http.Request, correlationID string, err error) {
+		logged = append(logged, correlationID+" "+err.Error())
+	}
+
+	t.Run("should hide 5xx details and use the request id as correlation id", func(t *testing.T) {
+		logged = nil
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			render.JSONAPI(w, r, render.Errors{
+				errors.New(`pq: relation "blogs" does not exist`),
+				&render.Error{Status: http.StatusUnprocessableEntity, Detail: "title is required"},
+			})
+		})
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs", nil)
+		r.Header.Set("X-Request-Id", "req-1")
+		w := httptest.NewRecorder()
+		middleware.RequestID(next).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, `{"errors":[{"id":"req-1","title":"Internal Server Error","detail":"An internal error occurred (correlation id: req-1)","status":"500"},{"title":"Unprocessable Entity","detail":"title is required","status":"422"}]}`, strings.TrimSpace(w.Body.String()))
+		assert.Equal(t, []string{`req-1 pq: relation "blogs" does not exist`}, logged)
+	})
+
+	t.Run("should hide marshaling failures", func(t *testing.T) {
+		logged = nil
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs", nil)
+		w := httptest.NewRecorder()
+		render.JSONAPI(w, r, struct{ Secret string }{"internal"})
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "models should be")
+		if assert.Len(t, logged, 1) {
+			id := strings.Fields(logged[0])[0]
+			assert.Len(t, id, 16)
+			assert.Contains(t, w.Body.String(), `"id":"`+id+`"`)
+		}
+	})
+
+	t.Run("should not sanitize when disabled", func(t *testing.T) {
+		render.ErrorSanitizer = nil
+		logged = nil
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/blogs", nil)
+		w := httptest.NewRecorder()
+		render.JSONAPI(w, r, errors.New("something went wrong"))
+		assert.Equal(t, `{"errors":[{"title":"Internal Server Error","detail":"something went wrong","status":"500"}]}`, strings.TrimSpace(w.Body.String()))
+		assert.Empty(t, logged)
+	})
+}
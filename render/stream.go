@@ -0,0 +1,166 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	chi_render "github.com/go-chi/render"
+	"github.com/google/jsonapi"
+	"io"
+	"net/http"
+)
+
+// StreamFlushCount is the number of resources Stream writes between two flushes of the response,
+// values lower than or equal to 1 flush every resource
+var StreamFlushCount = 100
+
+// Iterator returns the next resource of a collection, a struct pointer, or io.EOF when there is none left
+type Iterator func() (interface{}, error)
+
+// ChanIterator returns an Iterator receiving resources from `ch` until it is closed,
+// an error received from `ch` fails the stream
+func ChanIterator(ch <-chan interface{}) Iterator {
+	return func() (interface{}, error) {
+		v, ok := <-ch
+		if !ok {
+			return nil, io.EOF
+		}
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// Stream renders the resources returned by `next` as a JSON:API collection, writing each resource as soon
+// as it is returned and flushing the response every StreamFlushCount resources so that large collections
+// are not held in memory.
+// Included resources are the exception: since `included` follows the primary data, the distinct included
+// resources of the whole collection are held in memory until the end of the stream, so that large exports
+// should not request includes (or restrict them to relationships with few distinct resources).
+// Top-level links, including pagination links, and meta are written after the primary data.
+// An error returned by the first call to `next` is rendered as an error document, a later error ends
+// the document with its error objects in the top-level meta `errors` member since JSON:API documents
+// cannot hold both `data` and `errors`
+func Stream(w http.ResponseWriter, r *http.Request, next Iterator) {
+
+	w.Header().Set("Content-Type", GetMediaTypeParams(r).MediaType())
+	addVary(w, "Accept")
+
+	v, err := next()
+	if err == nil {
+		err = validateRequest(r, v)
+	}
+	if err != nil && err != io.EOF {
+		errs := flattenErrors(err)
+		writeErrors(w, r, errorsStatus(errs), false, errs...)
+		return
+	}
+
+	if status, ok := r.Context().Value(chi_render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	s := &stream{w: w, r: r, seen: map[string]bool{}}
+	s.write([]byte(`{"data":[`))
+
+	for err == nil {
+		if err = s.resource(v); err != nil {
+			break
+		}
+		if StreamFlushCount <= 1 || s.count%StreamFlushCount == 0 {
+			s.flush()
+		}
+		if err = r.Context().Err(); err != nil {
+			break
+		}
+		v, err = next()
+	}
+	s.write([]byte("]"))
+	s.end(err)
+}
+
+// stream writes a collection document one resource at a time
+type stream struct {
+	w        http.ResponseWriter
+	r        *http.Request
+	count    int
+	included []*jsonapi.Node
+	seen     map[string]bool
+	err      error
+}
+
+// resource writes the primary data of `v` and keeps its included resources not yet seen
+func (s *stream) resource(v interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	payload, err := jsonapi.Marshal(v)
+	if err != nil {
+		return err
+	}
+	one, ok := payload.(*jsonapi.OnePayload)
+	if !ok || one.Data == nil {
+		return fmt.Errorf("cannot stream %T, expected a struct pointer", v)
+	}
+
+	included := one.Included
+	if include, ok := includePaths(s.r, v); ok {
+		included = includedNodes(include, []*jsonapi.Node{one.Data}, included)
+	}
+	nodes := []*jsonapi.Node{one.Data}
+	for _, node := range included {
+		if !s.seen[nodeKey(node)] {
+			s.seen[nodeKey(node)] = true
+			nodes = append(nodes, node)
+		}
+	}
+	applyFields(getFields(s.r.Context()), nodes)
+	s.included = append(s.included, nodes[1:]...)
+
+	b, err := json.Marshal(one.Data)
+	if err != nil {
+		return err
+	}
+	if s.count > 0 {
+		s.write([]byte(","))
+	}
+	s.write(b)
+	s.count++
+	return s.err
+}
+
+// end writes the members following the primary data, `err` is the error that ended the stream if not io.EOF
+func (s *stream) end(err error) {
+	doc := &document{Included: s.included}
+	if err != nil && err != io.EOF && s.err == nil {
+		errs := flattenErrors(err)
+		objs := toJSONAPIErrors(errorsStatus(errs), false, errs...)
+		sanitizeErrors(s.r, objs, errs)
+		doc.Meta = &jsonapi.Meta{"errors": objs}
+	}
+	doc.setTopLevel(s.r, true, s.count)
+
+	trailer, _ := json.Marshal(struct {
+		Included []*jsonapi.Node `json:"included,omitempty"`
+		Links    *jsonapi.Links  `json:"links,omitempty"`
+		Meta     *jsonapi.Meta   `json:"meta,omitempty"`
+		JSONAPI  *JSONAPIObject  `json:"jsonapi,omitempty"`
+	}{doc.Included, doc.Links, doc.Meta, doc.JSONAPI})
+	if len(trailer) > 2 {
+		s.write([]byte(","))
+		s.write(trailer[1 : len(trailer)-1])
+	}
+	s.write([]byte("}\n"))
+	s.flush()
+}
+
+func (s *stream) write(b []byte) {
+	if s.err == nil {
+		_, s.err = s.w.Write(b)
+	}
+}
+
+func (s *stream) flush() {
+	if f, ok := s.w.(http.Flusher); ok && s.err == nil {
+		f.Flush()
+	}
+}
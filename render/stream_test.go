@@ -0,0 +1,149 @@
+package render_test
+
+import (
+	"errors"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/google/jsonapi"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sliceIterator returns an Iterator over `posts` failing with `err` when exhausted, if not nil
+func sliceIterator(posts []*Post, err error) render.Iterator {
+	return func() (interface{}, error) {
+		if len(posts) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		post := posts[0]
+		posts = posts[1:]
+		return post, nil
+	}
+}
+
+func TestStream(t *testing.T) {
+	// google/jsonapi orders included resources randomly, posts only share a single comment
+	comment := &Comment{ID: 7, Body: "first"}
+	posts := func() []*Post {
+		return []*Post{
+			{ID: 1, Title: "one", Comments: []*Comment{comment}},
+			{ID: 2, Title: "two", Comments: []*Comment{comment}},
+			{ID: 3, Title: "three"},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(r *http.Request)
+	}{
+		{name: "plain"},
+		{name: "top-level members", setup: func(r *http.Request) {
+			render.Meta(r, jsonapi.Meta{"total": 3})
+			render.Links(r, jsonapi.Links{"self": "/posts"})
+			render.Pagination(r, paginator{})
+		}},
+		{name: "sparse fieldsets", setup: func(r *http.Request) {
+			render.Fields(r, map[string][]string{"posts": {"title"}})
+		}},
+		{name: "empty include", setup: func(r *http.Request) {
+			render.Include(r, []string{})
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run("should render as JSONAPI with "+test.name, func(t *testing.T) {
+			render.StreamFlushCount = 2
+			defer func() { render.StreamFlushCount = 100 }()
+
+			r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if test.setup != nil {
+				test.setup(r)
+			}
+			expected := httptest.NewRecorder()
+			render.JSONAPI(expected, r, posts())
+
+			w := httptest.NewRecorder()
+			render.Stream(w, r, sliceIterator(posts(), nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, w.Flushed)
+			assert.Equal(t, expected.Header(), w.Header())
+			assert.JSONEq(t, expected.Body.String(), w.Body.String())
+		})
+	}
+
+	t.Run("should render included resources once after the primary data", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		w := httptest.NewRecorder()
+		render.Stream(w, r, sliceIterator(posts(), nil))
+		body := w.Body.String()
+		assert.Equal(t, 1, strings.Count(body, `{"type":"comments","id":"7","attributes"`))
+		assert.True(t, strings.Index(body, `"included":[`) > strings.LastIndex(body, `"type":"posts"`))
+	})
+
+	t.Run("should flush every resource when StreamFlushCount is not positive", func(t *testing.T) {
+		render.StreamFlushCount = 0
+		defer func() { render.StreamFlushCount = 100 }()
+
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		w := httptest.NewRecorder()
+		render.Stream(w, r, sliceIterator(posts(), nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, w.Flushed)
+		assert.Equal(t, 3, strings.Count(w.Body.String(), `"type":"posts"`))
+	})
+
+	t.Run("should render an empty collection", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		w := httptest.NewRecorder()
+		render.Stream(w, r, sliceIterator(nil, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"data":[]}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should render an error document when failing before the first resource", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		w := httptest.NewRecorder()
+		render.Stream(w, r, sliceIterator(nil, &render.Error{Status: http.StatusServiceUnavailable}))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Service Unavailable","status":"503"}]}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should end the document cleanly when failing mid-stream", func(t *testing.T) {
+		ch := make(chan interface{})
+		go func() {
+			ch <- &Post{ID: 1}
+			ch <- errors.New("connection reset")
+			close(ch)
+		}()
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		w := httptest.NewRecorder()
+		render.Meta(r, jsonapi.Meta{"total": 3})
+		render.Stream(w, r, render.ChanIterator(ch))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"data":[{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":""},"relationships":{"comments":{"data":[]}}}],"meta":{"errors":[{"title":"Internal Server Error","detail":"connection reset","status":"500"}],"total":3}}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should fail on resources that are not struct pointers", func(t *testing.T) {
+		ch := make(chan interface{}, 2)
+		ch <- &Post{ID: 1}
+		ch <- []*Post{{ID: 2}}
+		close(ch)
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		w := httptest.NewRecorder()
+		render.Stream(w, r, render.ChanIterator(ch))
+		assert.Contains(t, w.Body.String(), `"meta":{"errors":[{"title":"Internal Server Error","detail":"cannot stream []*render_test.Post, expected a struct pointer","status":"500"}]}}`)
+	})
+}
+
+func TestJSONAPI_Iterator(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	w := httptest.NewRecorder()
+	render.JSONAPI(w, r, sliceIterator([]*Post{{ID: 1}}, nil))
+	assert.Equal(t, `{"data":[{"type":"posts","id":"1","attributes":{"blog_id":0,"body":"","title":""},"relationships":{"comments":{"data":[]}}}]}`, strings.TrimSpace(w.Body.String()))
+}
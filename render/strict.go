@@ -0,0 +1,155 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StrictCtxKey is the context key set by the Strict middleware
+var StrictCtxKey = &contextKey{"Strict"}
+
+var (
+	// topLevelMembers are the members of a request document, `errors` is only allowed in responses,
+	// see https://jsonapi.org/format/#document-top-level
+	topLevelMembers = []string{"data", "meta", "jsonapi", "links", "included"}
+	// resourceMembers are the members of a resource object, see https://jsonapi.org/format/#document-resource-objects
+	resourceMembers = []string{"type", "id", "lid", "attributes", "relationships", "links", "meta"}
+	// resourceIdentifierMembers are the members of a resource identifier object,
+	// see https://jsonapi.org/format/#document-resource-identifier-objects
+	resourceIdentifierMembers = []string{"type", "id", "lid", "meta"}
+)
+
+// Strict is a middleware enabling strict decoding of the requests of a route: DefaultDecoder reports
+// unknown top-level members, resource object members, attributes and relationships, see DecodeJSONAPIStrict.
+// BindRelationship reports unknown members of relationship documents and AtomicProcessor the ones of
+// the resource objects of operations
+func Strict(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), StrictCtxKey, true)))
+	}
+	return http.HandlerFunc(fn)
+}
+
+func isStrict(ctx context.Context) bool {
+	strict, _ := ctx.Value(StrictCtxKey).(bool)
+	return strict
+}
+
+// DecodeJSONAPIStrict decodes a JSON:API document into `v`, a struct pointer, like DecodeJSONAPI.
+// Unknown top-level members, resource object members, and attributes and relationships without a matching
+// `attr` or `relation` tag are returned as DecodeError instead of being ignored, as are included resources
+// of types that cannot be reached from `v` through relationships
+func DecodeJSONAPIStrict(r io.Reader, v interface{}) error {
+	defer io.Copy(ioutil.Discard, r)
+	_, err := decodeJSONAPI(r, v, decodeOptions{strict: true})
+	return err
+}
+
+// checkUnknownMembers returns a DecodeError for each member of the document `doc` unknown to `res`,
+// the resource of its primary data, or to the resources reachable from `res` for included resources
+func checkUnknownMembers(doc map[string]interface{}, res *resource.Resource) Errors {
+	errs := checkUnknownTopLevelMembers(doc)
+	data, _ := doc["data"].(map[string]interface{})
+	errs = append(errs, checkUnknownResourceMembers(data, "/data", res)...)
+
+	included, _ := doc["included"].([]interface{})
+	reachable := res.Reachable()
+	for i, item := range included {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pointer := "/included/" + strconv.Itoa(i)
+		typ, _ := object["type"].(string)
+		related, ok := reachable[typ]
+		if !ok {
+			errs = append(errs, newUnknownMemberError(pointer+"/type", "unknown_member", fmt.Sprintf("unknown resource type %q of included resource", typ)))
+			continue
+		}
+		errs = append(errs, checkUnknownResourceMembers(object, pointer, related)...)
+	}
+	return errs
+}
+
+// checkUnknownRelationshipMembers returns a DecodeError for each unknown member of the relationship document `body`
+func checkUnknownRelationshipMembers(body []byte) Errors {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+	errs := checkUnknownTopLevelMembers(doc)
+
+	var identifiers []map[string]interface{}
+	var pointers []string
+	switch data := doc["data"].(type) {
+	case map[string]interface{}:
+		identifiers, pointers = append(identifiers, data), append(pointers, "/data")
+	case []interface{}:
+		for i, item := range data {
+			if identifier, ok := item.(map[string]interface{}); ok {
+				identifiers, pointers = append(identifiers, identifier), append(pointers, "/data/"+strconv.Itoa(i))
+			}
+		}
+	}
+	for i, identifier := range identifiers {
+		for _, name := range unknownMembers(identifier, func(name string) bool { return contains(resourceIdentifierMembers, name) }) {
+			errs = append(errs, newUnknownMemberError(pointers[i]+"/"+escapePointer(name), "unknown_member", fmt.Sprintf("unknown resource identifier member %q", name)))
+		}
+	}
+	return errs
+}
+
+// checkUnknownTopLevelMembers returns a DecodeError for each unknown top-level member of the document `doc`
+func checkUnknownTopLevelMembers(doc map[string]interface{}) (errs Errors) {
+	for _, name := range unknownMembers(doc, func(name string) bool { return contains(topLevelMembers, name) }) {
+		errs = append(errs, newUnknownMemberError("/"+escapePointer(name), "unknown_member", fmt.Sprintf("unknown top-level member %q", name)))
+	}
+	return errs
+}
+
+// checkUnknownResourceMembers returns a DecodeError for each member of the resource object `object`,
+// at `pointer`, unknown to `res`
+func checkUnknownResourceMembers(object map[string]interface{}, pointer string, res *resource.Resource) (errs Errors) {
+	for _, name := range unknownMembers(object, func(name string) bool { return contains(resourceMembers, name) }) {
+		errs = append(errs, newUnknownMemberError(pointer+"/"+escapePointer(name), "unknown_member", fmt.Sprintf("unknown resource object member %q", name)))
+	}
+	attributes, _ := object["attributes"].(map[string]interface{})
+	for _, name := range unknownMembers(attributes, func(name string) bool { return res.Attribute(name) != nil }) {
+		errs = append(errs, newUnknownMemberError(pointer+"/attributes/"+escapePointer(name), "unknown_attribute", fmt.Sprintf("unknown attribute %q of resource type %q", name, res.Name)))
+	}
+	relationships, _ := object["relationships"].(map[string]interface{})
+	for _, name := range unknownMembers(relationships, func(name string) bool { return res.Relationship(name) != nil }) {
+		errs = append(errs, newUnknownMemberError(pointer+"/relationships/"+escapePointer(name), "unknown_relationship", fmt.Sprintf("unknown relationship %q of resource type %q", name, res.Name)))
+	}
+	return errs
+}
+
+// unknownMembers returns the sorted names of the members of `object` that are not `known`
+func unknownMembers(object map[string]interface{}, known func(name string) bool) []string {
+	var names []string
+	for name := range object {
+		if !known(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// escapePointer escapes `token` as a JSON Pointer reference token, see RFC 6901
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func newUnknownMemberError(pointer, code, detail string) error {
+	return &DecodeError{Pointer: pointer, Code: code, Err: errors.New(detail)}
+}
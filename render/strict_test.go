@@ -0,0 +1,117 @@
+package render_test
+
+import (
+	"bytes"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONAPIStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []*render.Error
+	}{
+		{
+			name: "known members",
+			body: `{"data":{"type":"blogs","id":"1","attributes":{"title":"The Best Blog"},"relationships":{"posts":{"data":[]}},"meta":{}},"meta":{},"jsonapi":{"version":"1.1"}}`,
+		},
+		{
+			name: "unknown members",
+			body: `{"data":{"type":"blogs","id":"1","attribute":{},"attributes":{"titel":"The Best Blog","a/b":1},"relationships":{"post":{"data":[]}}},"foo":1}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "unknown_member", Detail: `unknown top-level member "foo"`, Source: &render.ErrorSource{Pointer: "/foo"}},
+				{Status: http.StatusBadRequest, Code: "unknown_member", Detail: `unknown resource object member "attribute"`, Source: &render.ErrorSource{Pointer: "/data/attribute"}},
+				{Status: http.StatusBadRequest, Code: "unknown_attribute", Detail: `unknown attribute "a/b" of resource type "blogs"`, Source: &render.ErrorSource{Pointer: "/data/attributes/a~1b"}},
+				{Status: http.StatusBadRequest, Code: "unknown_attribute", Detail: `unknown attribute "titel" of resource type "blogs"`, Source: &render.ErrorSource{Pointer: "/data/attributes/titel"}},
+				{Status: http.StatusBadRequest, Code: "unknown_relationship", Detail: `unknown relationship "post" of resource type "blogs"`, Source: &render.ErrorSource{Pointer: "/data/relationships/post"}},
+			},
+		},
+		{
+			name: "errors member",
+			body: `{"data":{"type":"blogs","id":"1"},"errors":[]}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "unknown_member", Detail: `unknown top-level member "errors"`, Source: &render.ErrorSource{Pointer: "/errors"}},
+			},
+		},
+		{
+			name: "unknown members of included resources",
+			body: `{"data":{"type":"blogs","id":"1","relationships":{"posts":{"data":[{"type":"posts","id":"2"}]}}},"included":[{"type":"posts","id":"2","attributes":{"titel":"The Best Post"},"relationships":{"comments":{"data":[]}}},{"type":"people","id":"3"}]}`,
+			expected: []*render.Error{
+				{Status: http.StatusBadRequest, Code: "unknown_attribute", Detail: `unknown attribute "titel" of resource type "posts"`, Source: &render.ErrorSource{Pointer: "/included/0/attributes/titel"}},
+				{Status: http.StatusBadRequest, Code: "unknown_member", Detail: `unknown resource type "people" of included resource`, Source: &render.ErrorSource{Pointer: "/included/1/type"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v Blog
+			err := render.DecodeJSONAPIStrict(bytes.NewBufferString(test.body), &v)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Equal(t, test.expected, errorObjects(t, err))
+			}
+
+			assert.NoError(t, render.DecodeJSONAPI(bytes.NewBufferString(test.body), &Blog{}))
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	committed := false
+	router := chi.NewRouter()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var v Blog
+		if err := render.DefaultDecoder(r, &v); err != nil {
+			render.JSONAPI(w, r, err)
+		}
+	}
+	router.With(render.Strict).Post("/strict", handler)
+	router.Post("/lenient", handler)
+	router.With(render.Strict).Patch("/blogs/1/relationships/posts", func(w http.ResponseWriter, r *http.Request) {
+		if err := render.BindRelationship(r, &Blog{ID: 1}, "posts"); err != nil {
+			render.JSONAPI(w, r, err)
+		}
+	})
+	router.With(render.Strict).Post("/operations", newAtomicProcessor(&committed).ServeHTTP)
+
+	serve := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should report unknown members of the routes using the middleware", func(t *testing.T) {
+		w := serve(http.MethodPost, "/strict", "application/vnd.api+json", `{"data":{"type":"blogs","attributes":{"titel":"The Best Blog"}}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"unknown attribute \"titel\" of resource type \"blogs\"","status":"400","code":"unknown_attribute","source":{"pointer":"/data/attributes/titel"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should ignore unknown members of other routes", func(t *testing.T) {
+		w := serve(http.MethodPost, "/lenient", "application/vnd.api+json", `{"data":{"type":"blogs","attributes":{"titel":"The Best Blog"}}}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should report unknown members of relationship documents", func(t *testing.T) {
+		w := serve(http.MethodPatch, "/blogs/1/relationships/posts", "application/vnd.api+json", `{"data":[{"type":"posts","id":"2","attributes":{}}],"foo":1}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"unknown top-level member \"foo\"","status":"400","code":"unknown_member","source":{"pointer":"/foo"}},{"title":"Bad Request","detail":"unknown resource identifier member \"attributes\"","status":"400","code":"unknown_member","source":{"pointer":"/data/0/attributes"}}]}`, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("should report unknown members of atomic operations", func(t *testing.T) {
+		w := serve(http.MethodPost, "/operations", atomicMediaType, `{"atomic:operations":[{"op":"add","data":{"type":"posts","attributes":{"titel":"first"}}}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"errors":[{"title":"Bad Request","detail":"unknown attribute \"titel\" of resource type \"posts\"","status":"400","code":"unknown_attribute","source":{"pointer":"/atomic:operations/0/data/attributes/titel"}}]}`, strings.TrimSpace(w.Body.String()))
+		assert.False(t, committed)
+	})
+}
@@ -0,0 +1,223 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateTag is the struct tag holding the comma-separated validation rules of `attr` and `relation` fields,
+// e.g. `validate:"required,max=100"`
+const ValidateTag = "validate"
+
+// ValidationRule checks the value of a field against the rule parameter (e.g. `3` for `min=3`),
+// the returned error is the detail of the error object, e.g. "must be at least 3"
+type ValidationRule func(field reflect.Value, param string) error
+
+// ValidationRules holds the rules usable in ValidateTag by name, register custom rules (e.g. `uuid`) by adding them.
+// Rules other than `required` are not applied to nil pointers. `required` checks that the fields of decoded
+// resources (see DefaultDecoder) are present and not null, strings must also not be blank and slices and maps
+// not be empty while explicit zero numbers and booleans are values; it checks the value of the other ones
+var ValidationRules = map[string]ValidationRule{
+	"required": validateRequired,
+	"min":      validateMin,
+	"max":      validateMax,
+	"oneof":    validateOneOf,
+}
+
+// ValidationError is a validation rule failing on an attribute or relationship,
+// it renders as a 422 Unprocessable Entity error pointing to the member
+type ValidationError struct {
+	// Pointer is a JSON Pointer to the member, e.g. `/data/attributes/title`
+	Pointer string
+	// Rule is the name of the failing rule and Param its parameter, if any
+	Rule  string
+	Param string
+	// Err is the error returned by the rule
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// JSONAPIError implements ErrorObjecter, the rule is rendered in the `rule` meta member
+func (e *ValidationError) JSONAPIError() *Error {
+	meta := map[string]interface{}{"rule": e.Rule}
+	if e.Param != "" {
+		meta["param"] = e.Param
+	}
+	return &Error{
+		Status: http.StatusUnprocessableEntity,
+		Code:   "validation_failed",
+		Detail: e.Error(),
+		Source: &ErrorSource{Pointer: e.Pointer},
+		Meta:   meta,
+	}
+}
+
+// Validate applies the rules of ValidateTag to the attributes and relationships of `v`, a struct pointer,
+// and returns a ValidationError for each failing rule. DefaultDecoder validates decoded JSON:API resources,
+// only the attributes and relationships present in PATCH requests
+func Validate(v interface{}) error {
+	return validate(v, nil, false)
+}
+
+// validate validates the attributes and relationships of `v`, `required` checks the fields `present`
+// in the decoded document, or the value of the fields if nil. Only present fields are validated when `partial`
+func validate(v interface{}, present *PresentFields, partial bool) error {
+	rv := reflect.ValueOf(v)
+	res := resource.Of(rv.Type())
+	if res == nil || rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+	rv = rv.Elem()
+
+	var errs Errors
+	for _, attr := range res.Attributes {
+		if partial && !present.HasAttribute(attr.Name) {
+			continue
+		}
+		given := present.HasAttribute(attr.Name) && !present.IsNullAttribute(attr.Name)
+		if err := validateField(rv, attr, "/data/attributes/"+attr.Name, present != nil, given, &errs); err != nil {
+			return err
+		}
+	}
+	for _, rel := range res.Relationships {
+		if partial && !present.HasRelationship(rel.Name) {
+			continue
+		}
+		if err := validateField(rv, rel, "/data/relationships/"+rel.Name, present != nil, present.HasRelationship(rel.Name), &errs); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateField appends to `errs` a ValidationError for each rule failing on `f`, `required` checks
+// whether `f` is `given`, i.e. present and not null, when `decoded`.
+// It returns an error for unknown rules and invalid rule parameters
+func validateField(rv reflect.Value, f *resource.Field, pointer string, decoded, given bool, errs *Errors) error {
+	tag := rv.Type().Field(f.Index).Tag.Get(ValidateTag)
+	if tag == "" {
+		return nil
+	}
+	field := rv.Field(f.Index)
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		fn, ok := ValidationRules[name]
+		if !ok {
+			return fmt.Errorf("unknown validation rule %q of field %s", name, rv.Type().Field(f.Index).Name)
+		}
+		if err := checkRuleParam(name, param); err != nil {
+			return fmt.Errorf("invalid validation rule %q of field %s: %v", rule, rv.Type().Field(f.Index).Name, err)
+		}
+		if name == "required" && decoded {
+			if !given || (field.Kind() == reflect.Ptr && field.IsNil()) {
+				*errs = append(*errs, &ValidationError{Pointer: pointer, Rule: name, Err: errors.New("is required")})
+				continue
+			}
+			// explicit zero numbers and booleans are values, blank strings and empty slices and maps are not
+			switch reflect.Indirect(field).Kind() {
+			case reflect.String, reflect.Slice, reflect.Map:
+			default:
+				continue
+			}
+		}
+		if name != "required" && field.Kind() == reflect.Ptr && field.IsNil() {
+			continue
+		}
+		if err := fn(reflect.Indirect(field), param); err != nil {
+			*errs = append(*errs, &ValidationError{Pointer: pointer, Rule: name, Param: param, Err: err})
+		}
+	}
+	return nil
+}
+
+// checkRuleParam checks the parameter of the built-in rule `name`
+func checkRuleParam(name, param string) error {
+	switch name {
+	case "min", "max":
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			return fmt.Errorf("expected a number, got %q", param)
+		}
+	}
+	return nil
+}
+
+func validateRequired(field reflect.Value, param string) error {
+	switch field.Kind() {
+	case reflect.Invalid:
+		return errors.New("is required")
+	case reflect.String:
+		if strings.TrimSpace(field.String()) == "" {
+			return errors.New("must not be blank")
+		}
+	case reflect.Slice, reflect.Map:
+		if field.Len() == 0 {
+			return errors.New("must not be empty")
+		}
+	default:
+		if field.IsZero() {
+			return errors.New("is required")
+		}
+	}
+	return nil
+}
+
+func validateMin(field reflect.Value, param string) error {
+	return validateBound(field, param, func(n, bound float64) bool { return n >= bound }, "at least")
+}
+
+func validateMax(field reflect.Value, param string) error {
+	return validateBound(field, param, func(n, bound float64) bool { return n <= bound }, "at most")
+}
+
+// validateBound checks numbers, and the length of strings, slices and maps, against `param`
+func validateBound(field reflect.Value, param string, ok func(n, bound float64) bool, qualifier string) error {
+	// the bound is checked by validateField
+	bound, _ := strconv.ParseFloat(param, 64)
+	var n float64
+	unit := ""
+	switch field.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(field.String())), " characters long"
+	case reflect.Slice, reflect.Map:
+		n, unit = float64(field.Len()), " items long"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = field.Float()
+	default:
+		return nil
+	}
+	if !ok(n, bound) {
+		return fmt.Errorf("must be %s %s%s", qualifier, param, unit)
+	}
+	return nil
+}
+
+func validateOneOf(field reflect.Value, param string) error {
+	options := strings.Fields(param)
+	if contains(options, fmt.Sprint(field.Interface())) {
+		return nil
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(options, ", "))
+}
@@ -0,0 +1,193 @@
+package render_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/fjgal/go-chi-jsonapi/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Article struct {
+	ID       int      `jsonapi:"primary,articles"`
+	Title    string   `jsonapi:"attr,title" validate:"required,min=3,max=20"`
+	Status   string   `jsonapi:"attr,status" validate:"oneof=draft published"`
+	Rating   *int     `jsonapi:"attr,rating" validate:"min=1,max=5"`
+	Tags     []string `jsonapi:"attr,tags" validate:"max=2"`
+	Slug     string   `jsonapi:"attr,slug" validate:"lowercase"`
+	Public   bool     `jsonapi:"attr,public" validate:"required"`
+	Author   *Author  `jsonapi:"relation,author" validate:"required"`
+	Untagged string   `jsonapi:"attr,untagged"`
+}
+
+func init() {
+	render.ValidationRules["lowercase"] = func(field reflect.Value, param string) error {
+		if field.String() != strings.ToLower(field.String()) {
+			return errors.New("must be lowercase")
+		}
+		return nil
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rating := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		v        *Article
+		expected []*render.Error
+	}{
+		{
+			name: "valid",
+			v:    &Article{Title: "Validation", Status: "draft", Rating: rating(5), Tags: []string{"go"}, Slug: "validation", Public: true, Author: &Author{ID: 1}},
+		},
+		{
+			name: "nil pointer",
+			v:    &Article{Title: "Validation", Status: "published", Public: true, Author: &Author{ID: 1}},
+		},
+		{
+			name: "invalid",
+			v:    &Article{Title: " ", Status: "deleted", Rating: rating(0), Tags: []string{"a", "b", "c"}, Slug: "Validation"},
+			expected: []*render.Error{
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "must not be blank", Source: &render.ErrorSource{Pointer: "/data/attributes/title"}, Meta: map[string]interface{}{"rule": "required"}},
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "must be at least 3 characters long", Source: &render.ErrorSource{Pointer: "/data/attributes/title"}, Meta: map[string]interface{}{"rule": "min", "param": "3"}},
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "must be one of draft, published", Source: &render.ErrorSource{Pointer: "/data/attributes/status"}, Meta: map[string]interface{}{"rule": "oneof", "param": "draft published"}},
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "must be at least 1", Source: &render.ErrorSource{Pointer: "/data/attributes/rating"}, Meta: map[string]interface{}{"rule": "min", "param": "1"}},
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "must be at most 2 items long", Source: &render.ErrorSource{Pointer: "/data/attributes/tags"}, Meta: map[string]interface{}{"rule": "max", "param": "2"}},
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "must be lowercase", Source: &render.ErrorSource{Pointer: "/data/attributes/slug"}, Meta: map[string]interface{}{"rule": "lowercase"}},
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "is required", Source: &render.ErrorSource{Pointer: "/data/attributes/public"}, Meta: map[string]interface{}{"rule": "required"}},
+				{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: "is required", Source: &render.ErrorSource{Pointer: "/data/relationships/author"}, Meta: map[string]interface{}{"rule": "required"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := render.Validate(test.v)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Equal(t, test.expected, errorObjects(t, err))
+			}
+		})
+	}
+}
+
+func TestValidateInvalidRules(t *testing.T) {
+
+	t.Run("should return an error for unknown rules", func(t *testing.T) {
+		v := &struct {
+			ID   int    `jsonapi:"primary,things"`
+			Name string `jsonapi:"attr,name" validate:"unknown"`
+		}{}
+		assert.EqualError(t, render.Validate(v), `unknown validation rule "unknown" of field Name`)
+	})
+
+	t.Run("should return an error for invalid rule parameters", func(t *testing.T) {
+		v := &struct {
+			ID   int    `jsonapi:"primary,things"`
+			Name string `jsonapi:"attr,name" validate:"min=abc"`
+		}{}
+		err := render.Validate(v)
+		assert.EqualError(t, err, `invalid validation rule "min=abc" of field Name: expected a number, got "abc"`)
+		var objecter render.ErrorObjecter
+		assert.False(t, errors.As(err, &objecter))
+	})
+}
+
+func TestDefaultDecoderValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "create",
+			method:       http.MethodPost,
+			body:         `{"data":{"type":"articles","attributes":{"title":"Go","status":"draft"}}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"errors":[{"title":"Unprocessable Entity","detail":"must be at least 3 characters long","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/title"},"meta":{"param":"3","rule":"min"}},{"title":"Unprocessable Entity","detail":"is required","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/public"},"meta":{"rule":"required"}},{"title":"Unprocessable Entity","detail":"is required","status":"422","code":"validation_failed","source":{"pointer":"/data/relationships/author"},"meta":{"rule":"required"}}]}`,
+		},
+		{
+			name:         "create with zero values",
+			method:       http.MethodPost,
+			body:         `{"data":{"type":"articles","attributes":{"title":"Validation","status":"draft","public":false},"relationships":{"author":{"data":{"type":"authors","id":"1"}}}}}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "create with null relationship",
+			method:       http.MethodPost,
+			body:         `{"data":{"type":"articles","attributes":{"title":"Validation","status":"draft","public":true},"relationships":{"author":{"data":null}}}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"errors":[{"title":"Unprocessable Entity","detail":"is required","status":"422","code":"validation_failed","source":{"pointer":"/data/relationships/author"},"meta":{"rule":"required"}}]}`,
+		},
+		{
+			name:         "create with null attributes",
+			method:       http.MethodPost,
+			body:         `{"data":{"type":"articles","attributes":{"title":null,"status":"draft","public":null},"relationships":{"author":{"data":{"type":"authors","id":"1"}}}}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"errors":[{"title":"Unprocessable Entity","detail":"is required","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/title"},"meta":{"rule":"required"}},{"title":"Unprocessable Entity","detail":"must be at least 3 characters long","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/title"},"meta":{"param":"3","rule":"min"}},{"title":"Unprocessable Entity","detail":"is required","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/public"},"meta":{"rule":"required"}}]}`,
+		},
+		{
+			name:         "create with blank string",
+			method:       http.MethodPost,
+			body:         `{"data":{"type":"articles","attributes":{"title":"","status":"draft","public":true},"relationships":{"author":{"data":{"type":"authors","id":"1"}}}}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"errors":[{"title":"Unprocessable Entity","detail":"must not be blank","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/title"},"meta":{"rule":"required"}},{"title":"Unprocessable Entity","detail":"must be at least 3 characters long","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/title"},"meta":{"param":"3","rule":"min"}}]}`,
+		},
+		{
+			name:         "update with null attribute",
+			method:       http.MethodPatch,
+			body:         `{"data":{"type":"articles","id":"1","attributes":{"public":null}}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"errors":[{"title":"Unprocessable Entity","detail":"is required","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/public"},"meta":{"rule":"required"}}]}`,
+		},
+		{
+			name:         "update validates present fields",
+			method:       http.MethodPatch,
+			body:         `{"data":{"type":"articles","id":"1","attributes":{"status":"draft"}}}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "update with invalid field",
+			method:       http.MethodPatch,
+			body:         `{"data":{"type":"articles","id":"1","attributes":{"title":"Go"}}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"errors":[{"title":"Unprocessable Entity","detail":"must be at least 3 characters long","status":"422","code":"validation_failed","source":{"pointer":"/data/attributes/title"},"meta":{"param":"3","rule":"min"}}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/articles", bytes.NewBufferString(test.body))
+			r.Header.Set("Content-Type", "application/vnd.api+json")
+			w := httptest.NewRecorder()
+			var v Article
+			if err := render.DefaultDecoder(r, &v); err != nil {
+				render.JSONAPI(w, r, err)
+			}
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+
+	t.Run("should respond invalid rule parameters with 500 Internal Server Error", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/things", bytes.NewBufferString(`{"data":{"type":"things","attributes":{"name":"thing"}}}`))
+		r.Header.Set("Content-Type", "application/vnd.api+json")
+		w := httptest.NewRecorder()
+		v := &struct {
+			ID   int    `jsonapi:"primary,things"`
+			Name string `jsonapi:"attr,name" validate:"min=abc"`
+		}{}
+		if err := render.DefaultDecoder(r, v); err != nil {
+			render.JSONAPI(w, r, err)
+		}
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "validation_failed")
+	})
+}
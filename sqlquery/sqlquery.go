@@ -0,0 +1,205 @@
+// Package sqlquery translates parsed JSON:API query parameters (filter conditions, sort fields and page)
+// into parameterized `WHERE`, `ORDER BY` and `LIMIT`/`OFFSET` clauses for database/sql
+package sqlquery
+
+import (
+	"fmt"
+	"github.com/fjgal/go-chi-jsonapi/internal/resource"
+	"github.com/fjgal/go-chi-jsonapi/queryparams"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ColumnTag is the struct tag naming the column of an attribute, e.g. `db:"created_at"`,
+// attributes without it are mapped to a column named after the attribute
+var ColumnTag = "db"
+
+// Dialect defines the placeholder and identifier quoting styles of a database
+type Dialect struct {
+	// Placeholder returns the placeholder of the n-th argument, starting at 1
+	Placeholder func(n int) string
+	// Quote quotes an identifier such as a column name
+	Quote func(name string) string
+}
+
+var (
+	// Postgres uses `$1` placeholders and double quoted identifiers
+	Postgres = Dialect{
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		Quote:       quoteWith(`"`),
+	}
+	// MySQL uses `?` placeholders and backquoted identifiers
+	MySQL = Dialect{
+		Placeholder: func(n int) string { return "?" },
+		Quote:       quoteWith("`"),
+	}
+	// SQLite uses `?` placeholders and double quoted identifiers
+	SQLite = Dialect{
+		Placeholder: func(n int) string { return "?" },
+		Quote:       quoteWith(`"`),
+	}
+)
+
+// Clause is a parameterized SQL fragment
+type Clause struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Builder builds clauses on the columns of a model
+type Builder struct {
+	Dialect Dialect
+	// ArgOffset is the number of arguments preceding the clause in the statement, so that
+	// numbered placeholders (e.g. Postgres `$1`) continue from it
+	ArgOffset int
+
+	res *resource.Resource
+}
+
+// New returns a Builder for `model`, a struct pointer or a slice of struct pointers annotated with jsonapi tags
+func New(dialect Dialect, model interface{}) (*Builder, error) {
+	res := resource.Of(reflect.TypeOf(model))
+	if res == nil {
+		return nil, queryparams.ErrNotResource
+	}
+	return &Builder{Dialect: dialect, res: res}, nil
+}
+
+// Build returns the `WHERE`, `ORDER BY` and `LIMIT`/`OFFSET` clauses of `conditions`, `sort` and `page`,
+// to be appended to a `SELECT` statement. Each clause is omitted when empty, `page` may be nil.
+// Cursors of cursor-based pagination are left to the caller, only the page size is applied
+func (b *Builder) Build(conditions []queryparams.Condition, sort []queryparams.SortField, page *queryparams.Page) (*Clause, error) {
+	clause := &Clause{Args: []interface{}{}}
+	var parts []string
+
+	if len(conditions) > 0 {
+		where, err := b.where(clause, conditions)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, where)
+	}
+
+	if len(sort) > 0 {
+		orderBy, err := b.orderBy(sort)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, orderBy)
+	}
+
+	if page != nil && page.Limit > 0 {
+		limit := "LIMIT " + b.arg(clause, page.Limit)
+		if page.Offset > 0 {
+			limit += " OFFSET " + b.arg(clause, page.Offset)
+		}
+		parts = append(parts, limit)
+	}
+
+	clause.SQL = strings.Join(parts, " ")
+	return clause, nil
+}
+
+// FromRequest builds the clauses of the conditions stored by queryparams.FilterBy, the sort fields stored by
+// queryparams.Middleware and the page stored by queryparams.Paginate
+func (b *Builder) FromRequest(r *http.Request) (*Clause, error) {
+	var sort []queryparams.SortField
+	if params := queryparams.FromContext(r.Context()); params != nil {
+		sort = params.Sort
+	}
+	return b.Build(queryparams.GetConditions(r.Context()), sort, queryparams.GetPage(r.Context()))
+}
+
+// Column returns the quoted column of the attribute `name`
+func (b *Builder) Column(name string) (string, error) {
+	attr := b.res.Attribute(name)
+	if attr == nil {
+		return "", fmt.Errorf("unknown attribute %q", name)
+	}
+	column := b.res.Type.Field(attr.Index).Tag.Get(ColumnTag)
+	if column == "-" {
+		return "", fmt.Errorf("attribute %q is not mapped to a column", name)
+	}
+	if column == "" {
+		column = name
+	}
+	return b.Dialect.Quote(column), nil
+}
+
+func (b *Builder) where(clause *Clause, conditions []queryparams.Condition) (string, error) {
+	predicates := make([]string, 0, len(conditions))
+	for _, c := range conditions {
+		column, err := b.Column(c.Field)
+		if err != nil {
+			return "", &queryparams.Error{Parameter: c.Parameter(), Detail: fmt.Sprintf("filtering by %q is not allowed", c.Field)}
+		}
+		if len(c.Values) == 0 {
+			return "", &queryparams.Error{Parameter: c.Parameter(), Detail: "no value given"}
+		}
+
+		operator := c.Operator
+		if operator == "" {
+			operator = queryparams.OpEq
+		}
+		var predicate string
+		switch operator {
+		case queryparams.OpEq, queryparams.OpNe, queryparams.OpLt, queryparams.OpLte,
+			queryparams.OpGt, queryparams.OpGte, queryparams.OpLike:
+			predicate = column + " " + comparisons[operator] + " " + b.arg(clause, c.Values[0])
+		case queryparams.OpIn:
+			placeholders := make([]string, len(c.Values))
+			for i, v := range c.Values {
+				placeholders[i] = b.arg(clause, v)
+			}
+			predicate = column + " IN (" + strings.Join(placeholders, ", ") + ")"
+		case queryparams.OpBetween:
+			if len(c.Values) != 2 {
+				return "", &queryparams.Error{Parameter: c.Parameter(), Detail: "must be given as two comma separated values"}
+			}
+			predicate = column + " BETWEEN " + b.arg(clause, c.Values[0]) + " AND " + b.arg(clause, c.Values[1])
+		default:
+			return "", &queryparams.Error{Parameter: c.Parameter(), Detail: fmt.Sprintf("unsupported operator %q", c.Operator)}
+		}
+		predicates = append(predicates, predicate)
+	}
+	return "WHERE " + strings.Join(predicates, " AND "), nil
+}
+
+func (b *Builder) orderBy(sort []queryparams.SortField) (string, error) {
+	columns := make([]string, 0, len(sort))
+	for _, f := range sort {
+		column, err := b.Column(f.Field)
+		if err != nil {
+			return "", &queryparams.Error{Parameter: queryparams.ParamSort, Detail: fmt.Sprintf("sorting by %q is not allowed", f.Field)}
+		}
+		if f.Descending {
+			column += " DESC"
+		}
+		columns = append(columns, column)
+	}
+	return "ORDER BY " + strings.Join(columns, ", "), nil
+}
+
+// arg adds `v` to the arguments of `clause` and returns its placeholder
+func (b *Builder) arg(clause *Clause, v interface{}) string {
+	clause.Args = append(clause.Args, v)
+	return b.Dialect.Placeholder(b.ArgOffset + len(clause.Args))
+}
+
+var comparisons = map[string]string{
+	queryparams.OpEq:   "=",
+	queryparams.OpNe:   "<>",
+	queryparams.OpLt:   "<",
+	queryparams.OpLte:  "<=",
+	queryparams.OpGt:   ">",
+	queryparams.OpGte:  ">=",
+	queryparams.OpLike: "LIKE",
+}
+
+func quoteWith(quote string) func(string) string {
+	return func(name string) string {
+		return quote + strings.Replace(name, quote, quote+quote, -1) + quote
+	}
+}
@@ -0,0 +1,155 @@
+package sqlquery_test
+
+import (
+	"database/sql"
+	"github.com/fjgal/go-chi-jsonapi/queryparams"
+	"github.com/fjgal/go-chi-jsonapi/sqlquery"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type Blog struct {
+	ID        int       `jsonapi:"primary,blogs"`
+	Title     string    `jsonapi:"attr,title"`
+	ViewCount int       `jsonapi:"attr,view_count" db:"views"`
+	CreatedAt time.Time `jsonapi:"attr,created_at"`
+	Secret    string    `jsonapi:"attr,secret" db:"-"`
+}
+
+func TestBuilder_Build(t *testing.T) {
+	conditions := []queryparams.Condition{
+		{Field: "title", Operator: queryparams.OpIn, Values: []interface{}{"a", "b"}},
+		{Field: "view_count", Operator: queryparams.OpBetween, Values: []interface{}{1, 10}},
+		{Field: "title", Operator: queryparams.OpLike, Values: []interface{}{"a%"}},
+	}
+	sort := []queryparams.SortField{{Field: "view_count", Descending: true}, {Field: "title"}}
+	page := &queryparams.Page{Offset: 20, Limit: 10}
+
+	tests := []struct {
+		name         string
+		dialect      sqlquery.Dialect
+		argOffset    int
+		conditions   []queryparams.Condition
+		sort         []queryparams.SortField
+		page         *queryparams.Page
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "postgres",
+			dialect:      sqlquery.Postgres,
+			argOffset:    1,
+			conditions:   conditions,
+			sort:         sort,
+			page:         page,
+			expectedSQL:  `WHERE "title" IN ($2, $3) AND "views" BETWEEN $4 AND $5 AND "title" LIKE $6 ORDER BY "views" DESC, "title" LIMIT $7 OFFSET $8`,
+			expectedArgs: []interface{}{"a", "b", 1, 10, "a%", 10, 20},
+		},
+		{
+			name:         "mysql",
+			dialect:      sqlquery.MySQL,
+			conditions:   conditions[:1],
+			sort:         sort[:1],
+			page:         &queryparams.Page{Limit: 5},
+			expectedSQL:  "WHERE `title` IN (?, ?) ORDER BY `views` DESC LIMIT ?",
+			expectedArgs: []interface{}{"a", "b", 5},
+		},
+		{
+			name:         "empty",
+			dialect:      sqlquery.SQLite,
+			expectedSQL:  "",
+			expectedArgs: []interface{}{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := sqlquery.New(test.dialect, &Blog{})
+			if assert.NoError(t, err) {
+				b.ArgOffset = test.argOffset
+				clause, err := b.Build(test.conditions, test.sort, test.page)
+				if assert.NoError(t, err) {
+					assert.Equal(t, test.expectedSQL, clause.SQL)
+					assert.Equal(t, test.expectedArgs, clause.Args)
+				}
+			}
+		})
+	}
+
+	t.Run("should fail on unknown or unmapped attributes", func(t *testing.T) {
+		b, _ := sqlquery.New(sqlquery.SQLite, &Blog{})
+		_, err := b.Build([]queryparams.Condition{{Field: "secret", Values: []interface{}{"s"}}}, nil, nil)
+		assert.Equal(t, &queryparams.Error{Parameter: "filter[secret]", Detail: `filtering by "secret" is not allowed`}, err)
+		_, err = b.Build([]queryparams.Condition{{Field: "posts", Operator: queryparams.OpIn, Values: []interface{}{1}}}, nil, nil)
+		assert.Equal(t, &queryparams.Error{Parameter: "filter[posts][in]", Detail: `filtering by "posts" is not allowed`}, err)
+		_, err = b.Build(nil, []queryparams.SortField{{Field: "posts"}}, nil)
+		assert.Equal(t, &queryparams.Error{Parameter: "sort", Detail: `sorting by "posts" is not allowed`}, err)
+		_, err = sqlquery.New(sqlquery.SQLite, struct{}{})
+		assert.Equal(t, queryparams.ErrNotResource, err)
+	})
+}
+
+func TestBuilder_FromRequest(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	_, err = db.Exec(`CREATE TABLE blogs (id INTEGER PRIMARY KEY, title TEXT, views INTEGER, created_at DATETIME)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, title := range []string{"go", "chi", "jsonapi", "sql", "gopher"} {
+		_, err = db.Exec(`INSERT INTO blogs VALUES (?, ?, ?, ?)`, i+1, title, i*10, now.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		expectedIDs []int
+	}{
+		{name: "filter", url: "/blogs?filter[view_count][gte]=20", expectedIDs: []int{3, 4, 5}},
+		{name: "filter and sort", url: "/blogs?filter[title][like]=go%25&sort=-view_count", expectedIDs: []int{5, 1}},
+		{name: "in", url: "/blogs?filter[title][in]=chi,sql&sort=title", expectedIDs: []int{2, 4}},
+		{name: "page", url: "/blogs?sort=-created_at&page[number]=2&page[size]=2", expectedIDs: []int{3, 2}},
+		{name: "between times", url: "/blogs?filter[created_at][between]=" + now.Add(time.Hour).Format(time.RFC3339) + "," + now.Add(3*time.Hour).Format(time.RFC3339), expectedIDs: []int{2, 3, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ids []int
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := sqlquery.New(sqlquery.SQLite, []*Blog{})
+				clause, err := b.FromRequest(r)
+				if !assert.NoError(t, err) {
+					return
+				}
+				rows, err := db.Query("SELECT id FROM blogs "+clause.SQL, clause.Args...)
+				if !assert.NoError(t, err) {
+					return
+				}
+				defer rows.Close()
+				for rows.Next() {
+					var id int
+					assert.NoError(t, rows.Scan(&id))
+					ids = append(ids, id)
+				}
+			})
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			w := httptest.NewRecorder()
+			handler := queryparams.FilterBy(&Blog{})(queryparams.Paginate(queryparams.PageNumber{})(next))
+			queryparams.Middleware(handler).ServeHTTP(w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, test.expectedIDs, ids)
+		})
+	}
+}